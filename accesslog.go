@@ -0,0 +1,215 @@
+package jsonapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// redacted replaces the values of redacted log attributes.
+const redacted = "[REDACTED]"
+
+// AccessLogger writes one structured log record per request with log/slog.
+//
+// As a middleware, it logs every request handled by the wrapped handler,
+// with its method, path, route pattern, status code, latency, size and
+// request ID. Responses written with a JSONResponder whose Observer is the
+// same AccessLogger add their error code and, for 5xx responses, the causes
+// of their error to the record of their request.
+//
+// As an Observer on its own, it logs one record per response written with
+// the JSONResponder.
+type AccessLogger struct {
+	// Logger is the logger records are written to. If nil, slog.Default()
+	// is used.
+	Logger *slog.Logger
+
+	// Level returns the level of the record of a response with the status
+	// code status. If nil, responses below 400 are logged at Info, 4xx at
+	// Warn and 5xx at Error.
+	Level func(status int) slog.Level
+
+	// Sample, if set, is called before a record is written, and the record
+	// is dropped if it returns false. Use it to log a fraction of successful
+	// requests, for example.
+	Sample func(r *http.Request, status int) bool
+
+	// Redact lists the keys of attributes whose values are replaced with
+	// "[REDACTED]", including attributes added with AddLogAttrs.
+	Redact []string
+}
+
+// accessLog is the state of the record of a request, stored in its context.
+type accessLog struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+	o     *Observation
+}
+
+type accessLogKey struct{}
+
+// AddLogAttrs adds attrs to the access log record of the request with the
+// context ctx. It does nothing if the request is not handled by an
+// AccessLogger.
+func AddLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	al, ok := ctx.Value(accessLogKey{}).(*accessLog)
+	if !ok {
+		return
+	}
+	al.mu.Lock()
+	al.attrs = append(al.attrs, attrs...)
+	al.mu.Unlock()
+}
+
+// Handler returns a handler that logs every request handled by next. The
+// responses written by next are made request-aware, see WithRequest.
+func (l *AccessLogger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		al := &accessLog{}
+		r = r.WithContext(context.WithValue(r.Context(), accessLogKey{}, al))
+		lw := &logWriter{ResponseWriter: w}
+
+		defer func() {
+			status := lw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			al.mu.Lock()
+			defer al.mu.Unlock()
+
+			pattern := r.Pattern
+			var o Observation
+			if al.o != nil {
+				o = *al.o
+				if o.Pattern != "" {
+					pattern = o.Pattern
+				}
+				if o.ErrorKind == ErrorKindClientClosed {
+					status = o.Status
+				}
+			}
+			l.log(r, status, time.Since(start), pattern, lw.bytes, o, al.attrs)
+		}()
+
+		next.ServeHTTP(WithRequest(lw, r), r)
+	})
+}
+
+// ObserveResponse implements Observer. Responses to requests handled by l
+// are added to their record, and others are logged on their own.
+func (l *AccessLogger) ObserveResponse(o Observation) {
+	if o.Request != nil {
+		if al, ok := o.Request.Context().Value(accessLogKey{}).(*accessLog); ok {
+			al.mu.Lock()
+			al.o = &o
+			al.mu.Unlock()
+			return
+		}
+	}
+	l.log(o.Request, o.Status, o.EncodeDuration, o.Pattern, o.Bytes, o, nil)
+}
+
+// log writes the record of a response.
+func (l *AccessLogger) log(r *http.Request, status int, latency time.Duration, pattern string, bytes int64, o Observation, extra []slog.Attr) {
+	if l.Sample != nil && !l.Sample(r, status) {
+		return
+	}
+
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := levelForStatus(status)
+	if l.Level != nil {
+		level = l.Level(status)
+	}
+
+	ctx := context.Background()
+	attrs := make([]slog.Attr, 0, 10+len(extra))
+	if r != nil {
+		ctx = r.Context()
+		attrs = append(attrs,
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+		)
+	}
+	if pattern != "" {
+		attrs = append(attrs, slog.String("pattern", pattern))
+	}
+	attrs = append(attrs,
+		slog.Int("status", status),
+		slog.Duration("latency", latency),
+		slog.Int64("bytes", bytes),
+	)
+	if r != nil {
+		if id := RequestIDFromContext(ctx); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+	}
+	if o.ErrorCode != "" {
+		attrs = append(attrs, slog.String("error_code", o.ErrorCode))
+	}
+	if o.ErrorKind != "" {
+		attrs = append(attrs, slog.String("error_kind", o.ErrorKind))
+	}
+	if o.Err != nil && status >= 500 {
+		attrs = append(attrs, slog.Any("causes", errorCauses(o.Err)))
+	}
+	attrs = append(attrs, extra...)
+
+	for i, a := range attrs {
+		if slices.Contains(l.Redact, a.Key) {
+			attrs[i] = slog.String(a.Key, redacted)
+		}
+	}
+
+	logger.LogAttrs(ctx, level, "request", attrs...)
+}
+
+// levelForStatus returns the default log level of a response with the status
+// code status.
+func levelForStatus(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// logWriter is an http.ResponseWriter that records the status code and size
+// of the response.
+type logWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (lw *logWriter) WriteHeader(code int) {
+	if lw.status == 0 && (code < 100 || code >= 200 || code == http.StatusSwitchingProtocols) {
+		lw.status = code
+	}
+	lw.ResponseWriter.WriteHeader(code)
+}
+
+func (lw *logWriter) Write(b []byte) (int, error) {
+	if lw.status == 0 {
+		lw.status = http.StatusOK
+	}
+	n, err := lw.ResponseWriter.Write(b)
+	lw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for
+// http.ResponseController.
+func (lw *logWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
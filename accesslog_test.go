@@ -0,0 +1,167 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// logRecords decodes the JSON log records written to b.
+func logRecords(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	dec := json.NewDecoder(b)
+	for dec.More() {
+		record := map[string]interface{}{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("expected %#v, got %#v", nil, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAccessLogger(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := &AccessLogger{
+		Logger: slog.New(slog.NewJSONHandler(b, nil)),
+		Redact: []string{"user_email"},
+	}
+	j := &JSONResponder{Observer: l}
+
+	mux := NewServeMux()
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		AddLogAttrs(r.Context(), slog.String("user_email", "a@example.com"), slog.Int("attempt", 2))
+		j.RespondError(w, errTestEmailTaken)
+	})
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		j.InternalServerError(w, fmt.Errorf("loading user: %w", errors.New("connection refused")))
+	})
+	mux.HandleFunc("GET /plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	h := Correlate(l.Handler(mux))
+
+	for _, test := range []struct {
+		method   string
+		target   string
+		expected map[string]interface{}
+	}{
+		{
+			method: http.MethodPost,
+			target: "/users",
+			expected: map[string]interface{}{
+				"level":      "WARN",
+				"msg":        "request",
+				"method":     "POST",
+				"path":       "/users",
+				"pattern":    "POST /users",
+				"status":     float64(http.StatusConflict),
+				"request_id": "abc-123",
+				"error_code": "user.email_taken",
+				"user_email": "[REDACTED]",
+				"attempt":    float64(2),
+			},
+		},
+		{
+			method: http.MethodGet,
+			target: "/users/1",
+			expected: map[string]interface{}{
+				"level":      "ERROR",
+				"msg":        "request",
+				"method":     "GET",
+				"path":       "/users/1",
+				"pattern":    "GET /users/{id}",
+				"status":     float64(http.StatusInternalServerError),
+				"request_id": "abc-123",
+				"causes":     []interface{}{"loading user: connection refused", "connection refused"},
+			},
+		},
+		{
+			method: http.MethodGet,
+			target: "/plain",
+			expected: map[string]interface{}{
+				"level":      "INFO",
+				"msg":        "request",
+				"method":     "GET",
+				"path":       "/plain",
+				"pattern":    "GET /plain",
+				"status":     float64(http.StatusOK),
+				"bytes":      float64(5),
+				"request_id": "abc-123",
+			},
+		},
+	} {
+		r := httptest.NewRequest(test.method, test.target, nil)
+		r.Header.Set(RequestIDHeader, "abc-123")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		records := logRecords(t, b)
+		if len(records) != 1 {
+			t.Errorf("expected %#v records, got %#v", 1, len(records))
+			continue
+		}
+		record := records[0]
+		if _, ok := record["latency"]; !ok {
+			t.Errorf("expected latency in %#v", record)
+		}
+		for key, value := range test.expected {
+			if v, _ := json.Marshal(record[key]); string(v) != mustMarshal(value) {
+				t.Errorf("expected %s %#v, got %#v", key, value, record[key])
+			}
+		}
+	}
+}
+
+func TestAccessLoggerSampleAndLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := &AccessLogger{
+		Logger: slog.New(slog.NewJSONHandler(b, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Level:  func(status int) slog.Level { return slog.LevelDebug },
+		Sample: func(r *http.Request, status int) bool { return status >= 400 },
+	}
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			NotFound(w)
+			return
+		}
+		OK(w)
+	}))
+
+	for _, target := range []string{"/", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	records := logRecords(t, b)
+	if len(records) != 1 || records[0]["path"] != "/missing" || records[0]["level"] != "DEBUG" {
+		t.Errorf("expected one DEBUG record of %#v, got %#v", "/missing", records)
+	}
+}
+
+func TestAccessLoggerObserver(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := &AccessLogger{Logger: slog.New(slog.NewJSONHandler(b, nil))}
+	j := &JSONResponder{Observer: l}
+
+	j.NotFound(httptest.NewRecorder())
+
+	records := logRecords(t, b)
+	if len(records) != 1 || records[0]["status"] != float64(http.StatusNotFound) {
+		t.Errorf("expected one record with status %#v, got %#v", http.StatusNotFound, records)
+	}
+	if _, ok := records[0]["method"]; ok {
+		t.Errorf("expected no method without request, got %#v", records[0])
+	}
+}
+
+func mustMarshal(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,268 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// AuthError describes why a request failed authentication or authorization.
+// Verifiers return it to control the status code and the error parameters of
+// the WWW-Authenticate challenge, as defined by RFC 6750.
+type AuthError struct {
+	// Status is the status code of the response, one of 400, 401 or 403.
+	Status int
+
+	// Code is the error code sent in the challenge, such as "invalid_token".
+	Code string
+
+	// Description is a human-readable explanation of the error. It is sent
+	// in the challenge and in the response body.
+	Description string
+
+	// Scope is the scope required to access the resource, if any.
+	Scope string
+}
+
+// Error implements the error interface.
+func (e *AuthError) Error() string {
+	if e.Description == "" {
+		return "jsonapi: " + e.Code
+	}
+	return "jsonapi: " + e.Code + ": " + e.Description
+}
+
+// Is reports whether target is an *AuthError with the same code, so that
+// errors.Is(err, ErrInvalidToken) matches any invalid token error.
+func (e *AuthError) Is(target error) bool {
+	t, ok := target.(*AuthError)
+	return ok && t.Code == e.Code
+}
+
+// Errors with the RFC 6750 error codes. Compare against them with errors.Is.
+var (
+	ErrInvalidRequest    = &AuthError{Status: http.StatusBadRequest, Code: "invalid_request"}
+	ErrInvalidToken      = &AuthError{Status: http.StatusUnauthorized, Code: "invalid_token"}
+	ErrInsufficientScope = &AuthError{Status: http.StatusForbidden, Code: "insufficient_scope"}
+)
+
+// InvalidRequest returns an error for malformed credentials, answered with
+// status code 400.
+func InvalidRequest(description string) error {
+	return &AuthError{Status: http.StatusBadRequest, Code: "invalid_request", Description: description}
+}
+
+// InvalidToken returns an error for credentials that are expired, revoked or
+// otherwise invalid, answered with status code 401.
+func InvalidToken(description string) error {
+	return &AuthError{Status: http.StatusUnauthorized, Code: "invalid_token", Description: description}
+}
+
+// InsufficientScope returns an error for valid credentials that do not grant
+// access to the resource, answered with status code 403.
+func InsufficientScope(scope, description string) error {
+	return &AuthError{Status: http.StatusForbidden, Code: "insufficient_scope", Description: description, Scope: scope}
+}
+
+// BearerVerifier verifies a bearer token and returns the principal it
+// identifies.
+type BearerVerifier func(r *http.Request, token string) (interface{}, error)
+
+// BasicVerifier verifies a username and password and returns the principal
+// they identify.
+type BasicVerifier func(r *http.Request, username, password string) (interface{}, error)
+
+// APIKeyVerifier verifies an API key and returns the principal it
+// identifies.
+type APIKeyVerifier func(r *http.Request, key string) (interface{}, error)
+
+// Authenticator is a middleware that authenticates requests with the
+// configured verifiers and stores the principal in the request context.
+//
+// Requests without credentials, and requests whose verifier fails, are
+// answered with Unauthorized and a WWW-Authenticate challenge for every
+// configured scheme. Verifier errors are mapped to a status code with
+// AuthError; any other error is treated as invalid credentials.
+type Authenticator struct {
+	// Realm is sent in the WWW-Authenticate challenges.
+	Realm string
+
+	// Bearer verifies "Authorization: Bearer" credentials.
+	Bearer BearerVerifier
+
+	// Basic verifies "Authorization: Basic" credentials.
+	Basic BasicVerifier
+
+	// APIKey verifies API keys sent in the APIKeyHeader header.
+	APIKey APIKeyVerifier
+
+	// APIKeyHeader is the header API keys are read from. It defaults to
+	// "X-API-Key".
+	APIKeyHeader string
+
+	// Optional lets requests without credentials through without a
+	// principal. Requests with invalid credentials are still rejected.
+	Optional bool
+
+	// Responder writes the responses to rejected requests. It defaults to
+	// DefaultResponder.
+	Responder *JSONResponder
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal stored in ctx by an
+// Authenticator.
+func PrincipalFromContext(ctx context.Context) (interface{}, bool) {
+	p := ctx.Value(principalKey{})
+	return p, p != nil
+}
+
+// ContextWithPrincipal returns a copy of ctx that carries principal.
+func ContextWithPrincipal(ctx context.Context, principal interface{}) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Handler returns a handler that authenticates requests to next.
+func (a *Authenticator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, principal, err := a.authenticate(r)
+		switch {
+		case err != nil:
+			a.reject(w, scheme, err)
+		case scheme == "":
+			if !a.Optional {
+				a.reject(w, scheme, nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+		}
+	})
+}
+
+// authenticate verifies the credentials of r. It returns the scheme of the
+// credentials, or "" if r has no credentials for a configured scheme.
+func (a *Authenticator) authenticate(r *http.Request) (string, interface{}, error) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, credentials, _ := strings.Cut(auth, " ")
+		switch {
+		case strings.EqualFold(scheme, "Bearer") && a.Bearer != nil:
+			token := strings.TrimSpace(credentials)
+			if token == "" {
+				return "Bearer", nil, InvalidRequest("missing bearer token")
+			}
+			principal, err := a.Bearer(r, token)
+			return "Bearer", principal, err
+		case strings.EqualFold(scheme, "Basic") && a.Basic != nil:
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				return "Basic", nil, InvalidRequest("malformed basic credentials")
+			}
+			principal, err := a.Basic(r, username, password)
+			return "Basic", principal, err
+		}
+	}
+
+	if a.APIKey != nil {
+		if key := r.Header.Get(a.apiKeyHeader()); key != "" {
+			principal, err := a.APIKey(r, key)
+			return "APIKey", principal, err
+		}
+	}
+
+	return "", nil, nil
+}
+
+// reject answers a request that failed authentication. scheme is the scheme
+// of the rejected credentials, or "" if there were none.
+func (a *Authenticator) reject(w http.ResponseWriter, scheme string, err error) {
+	authErr := &AuthError{Status: http.StatusUnauthorized}
+	if err != nil && !errors.As(err, &authErr) {
+		authErr = &AuthError{Status: http.StatusUnauthorized, Code: "invalid_token"}
+	}
+
+	for _, s := range []string{"Bearer", "Basic", "APIKey"} {
+		if !a.supports(s) {
+			continue
+		}
+		var challenge *AuthError
+		if s == scheme {
+			challenge = authErr
+		}
+		w.Header().Add("WWW-Authenticate", a.challenge(s, challenge))
+	}
+
+	j := responderOf(a.Responder)
+	if authErr.Description != "" {
+		j.Respond(w, authErr.status(), authErr.Description)
+		return
+	}
+	j.Respond(w, authErr.status())
+}
+
+// challenge returns the WWW-Authenticate challenge for scheme. The error
+// parameters are only included for the bearer scheme, as RFC 7617 does not
+// define them for basic authentication.
+func (a *Authenticator) challenge(scheme string, err *AuthError) string {
+	params := []string{"realm=" + quote(a.realm())}
+
+	switch scheme {
+	case "Basic":
+		params = append(params, `charset="UTF-8"`)
+	case "APIKey":
+		params = append(params, "header="+quote(a.apiKeyHeader()))
+	case "Bearer":
+		if err != nil && err.Code != "" {
+			params = append(params, "error="+quote(err.Code))
+			if err.Description != "" {
+				params = append(params, "error_description="+quote(err.Description))
+			}
+			if err.Scope != "" {
+				params = append(params, "scope="+quote(err.Scope))
+			}
+		}
+	}
+
+	return scheme + " " + strings.Join(params, ", ")
+}
+
+func (a *Authenticator) supports(scheme string) bool {
+	switch scheme {
+	case "Bearer":
+		return a.Bearer != nil
+	case "Basic":
+		return a.Basic != nil
+	case "APIKey":
+		return a.APIKey != nil
+	}
+	return false
+}
+
+func (a *Authenticator) realm() string {
+	if a.Realm == "" {
+		return "api"
+	}
+	return a.Realm
+}
+
+func (a *Authenticator) apiKeyHeader() string {
+	if a.APIKeyHeader == "" {
+		return "X-API-Key"
+	}
+	return a.APIKeyHeader
+}
+
+func (e *AuthError) status() int {
+	if e.Status == 0 {
+		return http.StatusUnauthorized
+	}
+	return e.Status
+}
+
+// quote returns s as an HTTP quoted-string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,215 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testAuthenticator() *Authenticator {
+	return &Authenticator{
+		Realm: "example",
+		Bearer: func(r *http.Request, token string) (interface{}, error) {
+			switch token {
+			case "valid":
+				return "alice", nil
+			case "expired":
+				return nil, InvalidToken("token expired")
+			case "readonly":
+				return nil, InsufficientScope("write", "token is read-only")
+			}
+			return nil, errors.New("unknown token")
+		},
+		Basic: func(r *http.Request, username, password string) (interface{}, error) {
+			if username == "bob" && password == "secret" {
+				return "bob", nil
+			}
+			return nil, InvalidToken("")
+		},
+	}
+}
+
+func authHandler(a *Authenticator) http.Handler {
+	return a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		OK(w, principal)
+	}))
+}
+
+func TestAuthenticatorAllowsValidCredentials(t *testing.T) {
+	h := authHandler(testAuthenticator())
+
+	for _, test := range []struct {
+		setup     func(r *http.Request)
+		principal string
+	}{
+		{setup: func(r *http.Request) { r.Header.Set("Authorization", "Bearer valid") }, principal: "alice"},
+		{setup: func(r *http.Request) { r.Header.Set("Authorization", "bearer valid") }, principal: "alice"},
+		{setup: func(r *http.Request) { r.SetBasicAuth("bob", "secret") }, principal: "bob"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		test.setup(r)
+		h.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %#v, got %#v", http.StatusOK, w.Code)
+		}
+		resp := &Response{}
+		if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if resp.Data != test.principal {
+			t.Errorf("expected %#v, got %#v", test.principal, resp.Data)
+		}
+	}
+}
+
+func TestAuthenticatorRejectsCredentials(t *testing.T) {
+	h := authHandler(testAuthenticator())
+
+	for _, test := range []struct {
+		auth       string
+		code       int
+		challenges []string
+	}{
+		{
+			auth: "",
+			code: http.StatusUnauthorized,
+			challenges: []string{
+				`Bearer realm="example"`,
+				`Basic realm="example", charset="UTF-8"`,
+			},
+		},
+		{
+			auth: "Bearer expired",
+			code: http.StatusUnauthorized,
+			challenges: []string{
+				`Bearer realm="example", error="invalid_token", error_description="token expired"`,
+				`Basic realm="example", charset="UTF-8"`,
+			},
+		},
+		{
+			auth: "Bearer unknown",
+			code: http.StatusUnauthorized,
+			challenges: []string{
+				`Bearer realm="example", error="invalid_token"`,
+				`Basic realm="example", charset="UTF-8"`,
+			},
+		},
+		{
+			auth: "Bearer readonly",
+			code: http.StatusForbidden,
+			challenges: []string{
+				`Bearer realm="example", error="insufficient_scope", error_description="token is read-only", scope="write"`,
+				`Basic realm="example", charset="UTF-8"`,
+			},
+		},
+		{
+			auth: "Bearer ",
+			code: http.StatusBadRequest,
+			challenges: []string{
+				`Bearer realm="example", error="invalid_request", error_description="missing bearer token"`,
+				`Basic realm="example", charset="UTF-8"`,
+			},
+		},
+		{
+			auth: "Basic Ym9iOndyb25n",
+			code: http.StatusUnauthorized,
+			challenges: []string{
+				`Bearer realm="example"`,
+				`Basic realm="example", charset="UTF-8"`,
+			},
+		},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		h.ServeHTTP(w, r)
+
+		if w.Code != test.code {
+			t.Errorf("%#v: expected status code %#v, got %#v", test.auth, test.code, w.Code)
+		}
+		challenges := w.Header().Values("WWW-Authenticate")
+		if len(challenges) != len(test.challenges) {
+			t.Errorf("%#v: expected %#v, got %#v", test.auth, test.challenges, challenges)
+			continue
+		}
+		for i := range challenges {
+			if challenges[i] != test.challenges[i] {
+				t.Errorf("%#v: expected %#v, got %#v", test.auth, test.challenges[i], challenges[i])
+			}
+		}
+	}
+}
+
+func TestAuthenticatorAPIKey(t *testing.T) {
+	h := authHandler(&Authenticator{
+		APIKey: func(r *http.Request, key string) (interface{}, error) {
+			if key == "k1" {
+				return "service", nil
+			}
+			return nil, InvalidToken("unknown key")
+		},
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", "k1")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %#v, got %#v", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-API-Key", "k2")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code %#v, got %#v", http.StatusUnauthorized, w.Code)
+	}
+	if c := w.Header().Get("WWW-Authenticate"); c != `APIKey realm="api", header="X-API-Key"` {
+		t.Errorf("expected %#v, got %#v", `APIKey realm="api", header="X-API-Key"`, c)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "unknown key" {
+		t.Errorf("expected %#v, got %#v", "unknown key", resp.Data)
+	}
+}
+
+func TestAuthenticatorOptional(t *testing.T) {
+	a := testAuthenticator()
+	a.Optional = true
+	h := authHandler(a)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %#v, got %#v", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer expired")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code %#v, got %#v", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestAuthErrorIs(t *testing.T) {
+	if !errors.Is(InvalidToken("expired"), ErrInvalidToken) {
+		t.Errorf("expected InvalidToken to match ErrInvalidToken")
+	}
+	if errors.Is(InsufficientScope("write", ""), ErrInvalidToken) {
+		t.Errorf("expected InsufficientScope not to match ErrInvalidToken")
+	}
+}
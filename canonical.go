@@ -0,0 +1,236 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrNotCanonicalizable is returned when JSON cannot be canonicalized, as
+// when it has duplicate object keys or numbers out of the range of IEEE 754
+// double precision numbers.
+var ErrNotCanonicalizable = errors.New("jsonapi: JSON cannot be canonicalized")
+
+// Canonical returns the canonical JSON encoding of v, as defined by the JSON
+// Canonicalization Scheme (RFC 8785): object keys are sorted by their UTF-16
+// code units, numbers are formatted like ECMAScript does, strings are
+// escaped minimally and there is no whitespace. Equal values have the same
+// canonical encoding, so it can be hashed or signed.
+//
+// v is encoded with encoding/json first, see Canonicalize.
+func Canonical(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(data)
+}
+
+// Canonicalize returns the canonical form of the JSON data, see Canonical.
+// Numbers are converted to IEEE 754 double precision numbers, so integers
+// beyond 2^53 lose precision, as they do in JavaScript. Data that is not
+// valid UTF-8, has duplicate object keys or numbers that overflow returns an
+// error.
+func Canonicalize(data []byte) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: invalid UTF-8", ErrNotCanonicalizable)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	out, err := appendCanonical(make([]byte, 0, len(data)), dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%w: data after the top-level value", ErrNotCanonicalizable)
+	}
+	return out, nil
+}
+
+// appendCanonical appends the canonical form of the next value of dec to b.
+func appendCanonical(b []byte, dec *json.Decoder) ([]byte, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, tok), nil
+	case string:
+		return appendCanonicalString(b, tok), nil
+	case json.Number:
+		f, err := strconv.ParseFloat(string(tok), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: number %s", ErrNotCanonicalizable, tok)
+		}
+		return appendCanonicalNumber(b, f)
+	case json.Delim:
+		if tok == '[' {
+			return appendCanonicalArray(b, dec)
+		}
+		return appendCanonicalObject(b, dec)
+	}
+	return nil, fmt.Errorf("%w: unexpected token %v", ErrNotCanonicalizable, tok)
+}
+
+func appendCanonicalArray(b []byte, dec *json.Decoder) ([]byte, error) {
+	b = append(b, '[')
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		var err error
+		if b, err = appendCanonical(b, dec); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return append(b, ']'), nil
+}
+
+type canonicalMember struct {
+	key   []uint16
+	value []byte
+}
+
+func appendCanonicalObject(b []byte, dec *json.Decoder) ([]byte, error) {
+	var members []canonicalMember
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		m := canonicalMember{key: utf16.Encode([]rune(key))}
+		m.value = appendCanonicalString(m.value, key)
+		m.value = append(m.value, ':')
+		if m.value, err = appendCanonical(m.value, dec); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(members, func(a, c canonicalMember) int {
+		return slices.Compare(a.key, c.key)
+	})
+
+	b = append(b, '{')
+	for i, m := range members {
+		if i > 0 {
+			if slices.Equal(m.key, members[i-1].key) {
+				return nil, fmt.Errorf("%w: duplicate key %q", ErrNotCanonicalizable, string(utf16.Decode(m.key)))
+			}
+			b = append(b, ',')
+		}
+		b = append(b, m.value...)
+	}
+	return append(b, '}'), nil
+}
+
+// appendCanonicalString appends s to b as a JSON string, escaping only
+// quotation marks, backslashes and control characters.
+func appendCanonicalString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c >= 0x20:
+			b = append(b, c)
+		case c == '\b':
+			b = append(b, '\\', 'b')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\f':
+			b = append(b, '\\', 'f')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		}
+	}
+	return append(b, '"')
+}
+
+// appendCanonicalNumber appends f to b the way ECMAScript converts numbers
+// to strings.
+func appendCanonicalNumber(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%w: number %v", ErrNotCanonicalizable, f)
+	}
+	if f == 0 {
+		return append(b, '0'), nil
+	}
+	if f < 0 {
+		b = append(b, '-')
+		f = -f
+	}
+
+	// The shortest digits that round-trip, and the exponent n such that
+	// f = 0.digits × 10^n.
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	e := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[e+1:])
+	digits := s[:1]
+	if e > 1 {
+		digits += s[2:e]
+	}
+	k, n := len(digits), exp+1
+
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		for i := k; i < n; i++ {
+			b = append(b, '0')
+		}
+	case 0 < n && n <= 21:
+		b = append(b, digits[:n]...)
+		b = append(b, '.')
+		b = append(b, digits[n:]...)
+	case -6 < n && n <= 0:
+		b = append(b, '0', '.')
+		for i := n; i < 0; i++ {
+			b = append(b, '0')
+		}
+		b = append(b, digits...)
+	default:
+		b = append(b, digits[0])
+		if k > 1 {
+			b = append(b, '.')
+			b = append(b, digits[1:]...)
+		}
+		b = append(b, 'e')
+		if n-1 >= 0 {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(n-1), 10)
+	}
+	return b, nil
+}
@@ -0,0 +1,146 @@
+package jsonapi
+
+import (
+	"embed"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testCanonical holds the examples of RFC 8785, sections 3.2.2 and 3.2.3,
+// with their canonical forms.
+//
+//go:embed testdata/canonical/*.json
+var testCanonical embed.FS
+
+func TestCanonicalize(t *testing.T) {
+	for _, name := range []string{"values", "sorting"} {
+		input, err := testCanonical.ReadFile("testdata/canonical/" + name + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := testCanonical.ReadFile("testdata/canonical/" + name + ".canonical.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := Canonicalize(input)
+		if err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if string(out) != string(expected) {
+			t.Errorf("expected %#v, got %#v", string(expected), string(out))
+		}
+	}
+}
+
+func TestCanonicalNumbers(t *testing.T) {
+	// The number serialization samples of RFC 8785, appendix B.
+	for _, test := range []struct {
+		bits     uint64
+		expected string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		out, err := appendCanonicalNumber(nil, math.Float64frombits(test.bits))
+		if err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if string(out) != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, string(out))
+		}
+	}
+
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := appendCanonicalNumber(nil, f); !errors.Is(err, ErrNotCanonicalizable) {
+			t.Errorf("expected %#v, got %#v", ErrNotCanonicalizable, err)
+		}
+	}
+}
+
+func TestCanonicalizeInvalid(t *testing.T) {
+	for _, input := range []string{
+		``,
+		`{"a":1`,
+		`{"a":1,"a":2}`,
+		`[1e400]`,
+		"\"\xff\"",
+		`{} {}`,
+	} {
+		if out, err := Canonicalize([]byte(input)); err == nil {
+			t.Errorf("expected an error for %#v, got %#v", input, string(out))
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	out, err := Canonical(struct {
+		Name  string  `json:"name"`
+		Score float64 `json:"score"`
+		ID    int     `json:"id"`
+	}{"<Ada & Grace>", 1.50, 7})
+	if err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if expected := `{"id":7,"name":"<Ada & Grace>","score":1.5}`; string(out) != expected {
+		t.Errorf("expected %#v, got %#v", expected, string(out))
+	}
+}
+
+func TestCanonicalRespond(t *testing.T) {
+	j := &JSONResponder{Canonical: true}
+
+	for _, test := range []struct {
+		respond  func(w http.ResponseWriter)
+		expected string
+	}{
+		{func(w http.ResponseWriter) { j.NotFound(w) }, `{"code":404,"data":"Not Found"}`},
+		{func(w http.ResponseWriter) { j.OK(w, map[string]interface{}{"b": 2.50, "a": "<"}) }, `{"code":200,"data":{"a":"<","b":2.5}}`},
+		{func(w http.ResponseWriter) { j.OK(w, Raw(`{ "z": 1e2, "y": [ ] }`)) }, `{"code":200,"data":{"y":[],"z":100}}`},
+		{func(w http.ResponseWriter) { j.respond(w, http.StatusOK, map[string]interface{}{"page": 1}, "x") }, `{"code":200,"data":"x","meta":{"page":1}}`},
+	} {
+		w := httptest.NewRecorder()
+		test.respond(w)
+
+		if w.Body.String() != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, w.Body.String())
+		}
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrNotCanonicalizable) {
+			t.Errorf("expected %#v, got %#v", ErrNotCanonicalizable, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), `"a"`) {
+			t.Errorf("expected the duplicate key in %#v", err.Error())
+		}
+	}()
+	j.OK(httptest.NewRecorder(), Raw(`{"a":1,"a":2}`))
+}
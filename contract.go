@@ -0,0 +1,165 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"unicode/utf8"
+)
+
+// Rules checked by a JSONResponder with an OnViolation hook.
+const (
+	RuleIgnoredData      = "ignored-data"
+	RuleBodyNotAllowed   = "body-not-allowed"
+	RuleMissingLocation  = "missing-location"
+	RuleMissingChallenge = "missing-www-authenticate"
+	RuleMissingAllow     = "missing-allow"
+	RuleInvalidUTF8      = "invalid-utf8"
+)
+
+// Limits of the search for invalid UTF-8 in response data, so that checking
+// large or cyclic payloads stays cheap.
+const (
+	maxUTF8CheckDepth  = 32
+	maxUTF8CheckValues = 1 << 12
+)
+
+// Violation describes a response that breaks the HTTP protocol or the
+// conventions of this package.
+type Violation struct {
+	// Request is the request of a request-aware response, or nil.
+	Request *http.Request
+
+	// Status is the status code of the response.
+	Status int
+
+	// Rule is the rule that was broken, one of the Rule constants.
+	Rule string
+
+	// Message explains the violation.
+	Message string
+
+	// CallSite is where the response was written.
+	CallSite CallSite
+}
+
+// String returns the violation with its call site.
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %d response: %s (%s)", v.CallSite, v.Status, v.Message, v.Rule)
+}
+
+// TestingT is the subset of testing.TB used by FailTest.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// FailTest returns an OnViolation hook that fails t for every violation.
+func FailTest(t TestingT) func(v Violation) {
+	return func(v Violation) {
+		t.Errorf("jsonapi: %s", v)
+	}
+}
+
+// check reports the contract violations of a response to j.OnViolation.
+func (j *JSONResponder) check(w http.ResponseWriter, statusCode int, data []interface{}) {
+	var site *CallSite
+	report := func(rule, format string, args ...interface{}) {
+		if site == nil {
+			s := callSite()
+			site = &s
+		}
+		j.OnViolation(Violation{
+			Request:  requestOf(w),
+			Status:   statusCode,
+			Rule:     rule,
+			Message:  fmt.Sprintf(format, args...),
+			CallSite: *site,
+		})
+	}
+
+	if len(data) > 1 {
+		report(RuleIgnoredData, "%d data arguments given, all but the first are ignored", len(data))
+	}
+	if info, _ := LookupStatus(statusCode); len(data) > 0 && !info.BodyAllowed {
+		report(RuleBodyNotAllowed, "data given for a status code that does not allow a body")
+	}
+
+	h := w.Header()
+	switch statusCode {
+	case http.StatusCreated:
+		if h.Get("Location") == "" {
+			report(RuleMissingLocation, "created resource without Location header")
+		}
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		if h.Get("Location") == "" {
+			report(RuleMissingLocation, "redirect without Location header")
+		}
+	case http.StatusUnauthorized:
+		if h.Get("WWW-Authenticate") == "" {
+			report(RuleMissingChallenge, "unauthorized without WWW-Authenticate header")
+		}
+	case http.StatusMethodNotAllowed:
+		if h.Get("Allow") == "" {
+			report(RuleMissingAllow, "method not allowed without Allow header")
+		}
+	}
+
+	if len(data) > 0 {
+		budget := maxUTF8CheckValues
+		if path, ok := findInvalidUTF8(reflect.ValueOf(data[0]), "data", 0, &budget); ok {
+			report(RuleInvalidUTF8, "invalid UTF-8 in %s", path)
+		}
+	}
+}
+
+// findInvalidUTF8 returns the path of the first string in v that is not
+// valid UTF-8. It gives up below a depth of maxUTF8CheckDepth, and after
+// visiting budget values.
+func findInvalidUTF8(v reflect.Value, path string, depth int, budget *int) (string, bool) {
+	*budget--
+	if depth > maxUTF8CheckDepth || *budget < 0 || !v.IsValid() {
+		return "", false
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if !utf8.ValidString(v.String()) {
+			return path, true
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return findInvalidUTF8(v.Elem(), path, depth+1, budget)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return "", false // Encoded as base64.
+		}
+		for i := 0; i < v.Len(); i++ {
+			if p, ok := findInvalidUTF8(v.Index(i), fmt.Sprintf("%s[%d]", path, i), depth+1, budget); ok {
+				return p, true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if k := iter.Key(); k.Kind() == reflect.String && !utf8.ValidString(k.String()) {
+				return fmt.Sprintf("%s key %q", path, key), true
+			}
+			if p, ok := findInvalidUTF8(iter.Value(), fmt.Sprintf("%s[%q]", path, key), depth+1, budget); ok {
+				return p, true
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if p, ok := findInvalidUTF8(v.Field(i), path+"."+t.Field(i).Name, depth+1, budget); ok {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
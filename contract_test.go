@@ -0,0 +1,122 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContractViolations(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		header map[string]string
+		f      func(j *JSONResponder, w http.ResponseWriter)
+		rules  []string
+	}{
+		{
+			name:  "ignored data",
+			f:     func(j *JSONResponder, w http.ResponseWriter) { j.OK(w, "a", "b") },
+			rules: []string{RuleIgnoredData},
+		},
+		{
+			name:  "body on 304",
+			f:     func(j *JSONResponder, w http.ResponseWriter) { j.NotModified(w, "stale") },
+			rules: []string{RuleBodyNotAllowed},
+		},
+		{
+			name:  "created without location",
+			f:     func(j *JSONResponder, w http.ResponseWriter) { j.Created(w) },
+			rules: []string{RuleMissingLocation},
+		},
+		{
+			name:   "created with location",
+			header: map[string]string{"Location": "/users/1"},
+			f:      func(j *JSONResponder, w http.ResponseWriter) { j.Created(w) },
+		},
+		{
+			name:  "redirect without location",
+			f:     func(j *JSONResponder, w http.ResponseWriter) { j.SeeOther(w) },
+			rules: []string{RuleMissingLocation},
+		},
+		{
+			name:  "unauthorized without challenge",
+			f:     func(j *JSONResponder, w http.ResponseWriter) { j.Unauthorized(w) },
+			rules: []string{RuleMissingChallenge},
+		},
+		{
+			name:   "unauthorized with challenge",
+			header: map[string]string{"WWW-Authenticate": `Bearer realm="api"`},
+			f:      func(j *JSONResponder, w http.ResponseWriter) { j.Unauthorized(w) },
+		},
+		{
+			name:  "method not allowed without allow",
+			f:     func(j *JSONResponder, w http.ResponseWriter) { j.MethodNotAllowed(w) },
+			rules: []string{RuleMissingAllow},
+		},
+		{
+			name: "invalid utf-8",
+			f: func(j *JSONResponder, w http.ResponseWriter) {
+				j.OK(w, map[string]interface{}{"users": []struct{ Name string }{{"ok"}, {"bad\xff"}}})
+			},
+			rules: []string{RuleInvalidUTF8},
+		},
+		{
+			name: "valid payload",
+			f: func(j *JSONResponder, w http.ResponseWriter) {
+				j.OK(w, map[string]interface{}{"name": "Zoë", "raw": []byte("\xff")})
+			},
+		},
+	} {
+		var rules []string
+		j := &JSONResponder{OnViolation: func(v Violation) {
+			rules = append(rules, v.Rule)
+			if !strings.HasSuffix(v.CallSite.File, "contract_test.go") {
+				t.Errorf("%s: expected call site in contract_test.go, got %s", test.name, v.CallSite)
+			}
+		}}
+
+		// A probeWriter accepts bodies for any status code, unlike
+		// httptest.ResponseRecorder.
+		w := &probeWriter{header: make(http.Header)}
+		for k, v := range test.header {
+			w.Header().Set(k, v)
+		}
+		test.f(j, w)
+
+		if fmt.Sprint(rules) != fmt.Sprint(test.rules) {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.rules, rules)
+		}
+	}
+}
+
+func TestContractInvalidUTF8Path(t *testing.T) {
+	var v Violation
+	j := &JSONResponder{OnViolation: func(got Violation) { v = got }}
+
+	j.OK(httptest.NewRecorder(), map[string]interface{}{"users": []struct{ Name string }{{"ok"}, {"bad\xff"}}})
+
+	if v.Message != `invalid UTF-8 in data["users"][1].Name` {
+		t.Errorf("expected %#v, got %#v", `invalid UTF-8 in data["users"][1].Name`, v.Message)
+	}
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFailTest(t *testing.T) {
+	rt := &recordingT{}
+	j := &JSONResponder{OnViolation: FailTest(rt)}
+
+	j.Created(httptest.NewRecorder())
+
+	if len(rt.errors) != 1 || !strings.Contains(rt.errors[0], "created resource without Location header") {
+		t.Errorf("expected a test failure, got %#v", rt.errors)
+	}
+}
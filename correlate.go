@@ -0,0 +1,196 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Headers of the IDs that correlate responses with server logs.
+const (
+	RequestIDHeader = "X-Request-ID"
+	TraceIDHeader   = "X-Trace-ID"
+)
+
+// maxRequestIDLength is the length of the longest request ID that is accepted
+// from a client.
+const maxRequestIDLength = 128
+
+// TraceContext is the W3C trace context of a request, parsed from its
+// traceparent header.
+type TraceContext struct {
+	// TraceID is the ID of the trace, as 32 lowercase hex digits.
+	TraceID string
+
+	// ParentID is the ID of the caller's span, as 16 lowercase hex digits.
+	ParentID string
+
+	// Flags are the trace flags. Bit 0 is set if the caller sampled the
+	// trace.
+	Flags byte
+}
+
+// Sampled reports whether the caller sampled the trace.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// ParseTraceparent parses the value of a W3C traceparent header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (TraceContext, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || (len(s) > 55 && s[55] != '-') {
+		return TraceContext{}, false
+	}
+	version, traceID, parentID, flags := s[0:2], s[3:35], s[36:52], s[53:55]
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return TraceContext{}, false
+	}
+	// Version 00 has no fields after the flags, and version ff is invalid.
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(s) != 55) {
+		return TraceContext{}, false
+	}
+	if !isLowerHex(traceID) || isZeroHex(traceID) || !isLowerHex(parentID) || isZeroHex(parentID) || !isLowerHex(flags) {
+		return TraceContext{}, false
+	}
+
+	f, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, ParentID: parentID, Flags: f[0]}, true
+}
+
+// Correlator is a middleware that gives every request a request ID and
+// parses its trace context, so that responses can be correlated with server
+// logs.
+//
+// The request ID is read from the X-Request-ID header, or generated if the
+// header is missing or not a short printable string. Both IDs are stored in
+// the request context and responses are made request-aware, so that every
+// response written with this package has the request ID and trace ID in its
+// meta field and in the X-Request-ID and X-Trace-ID headers.
+type Correlator struct {
+	// Header is the header the request ID is read from. If empty,
+	// X-Request-ID is used.
+	Header string
+
+	// NewID generates request IDs. If nil, random IDs of 32 hex digits
+	// are generated.
+	NewID func() string
+}
+
+// Correlate returns a handler that gives every request handled by next a
+// request ID and its trace context, with the default Correlator.
+func Correlate(next http.Handler) http.Handler {
+	return (&Correlator{}).Handler(next)
+}
+
+// Handler returns a handler that gives every request handled by next a
+// request ID and its trace context.
+func (c *Correlator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := c.Header
+		if header == "" {
+			header = RequestIDHeader
+		}
+
+		id := r.Header.Get(header)
+		if !validRequestID(id) {
+			if c.NewID != nil {
+				id = c.NewID()
+			} else {
+				id = randomHex(16)
+			}
+		}
+		ctx := ContextWithRequestID(r.Context(), id)
+
+		if tc, ok := ParseTraceparent(r.Header.Get("Traceparent")); ok {
+			ctx = ContextWithTrace(ctx, tc)
+		}
+
+		r = r.WithContext(ctx)
+		next.ServeHTTP(WithRequest(w, r), r)
+	})
+}
+
+type requestIDKey struct{}
+
+type traceKey struct{}
+
+// ContextWithRequestID returns a copy of ctx that carries the request ID id.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx by a Correlator,
+// or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ContextWithTrace returns a copy of ctx that carries the trace context tc.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext returns the trace context stored in ctx by a Correlator.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
+
+// correlate sets the headers that correlate the response to req with server
+// logs, and returns meta with the same IDs added.
+func correlate(w http.ResponseWriter, req *http.Request, meta map[string]interface{}) map[string]interface{} {
+	ctx := req.Context()
+	id := RequestIDFromContext(ctx)
+	tc, traced := TraceFromContext(ctx)
+	if id == "" && !traced {
+		return meta
+	}
+
+	m := make(map[string]interface{}, len(meta)+2)
+	for k, v := range meta {
+		m[k] = v
+	}
+	if id != "" {
+		w.Header().Set(RequestIDHeader, id)
+		m["request_id"] = id
+	}
+	if traced {
+		w.Header().Set(TraceIDHeader, tc.TraceID)
+		m["trace_id"] = tc.TraceID
+	}
+	return m
+}
+
+// validRequestID reports whether id may be used as a request ID: a non-empty
+// string of at most maxRequestIDLength printable ASCII characters, so that it
+// is safe to log and to echo in headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// isLowerHex reports whether s consists of lowercase hex digits.
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZeroHex reports whether s consists of zeros.
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,146 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		tc     TraceContext
+		ok     bool
+	}{
+		{
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			tc:     TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: 1},
+			ok:     true,
+		},
+		{
+			header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future",
+			tc:     TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7"},
+			ok:     true,
+		},
+		{header: ""},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{header: "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01"},
+	} {
+		tc, ok := ParseTraceparent(test.header)
+		if tc != test.tc || ok != test.ok {
+			t.Errorf("expected %#v %#v, got %#v %#v", test.tc, test.ok, tc, ok)
+		}
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	for _, test := range []struct {
+		requestID   string
+		traceparent string
+		generated   bool
+		traceID     string
+	}{
+		{requestID: "abc-123"},
+		{generated: true},
+		{requestID: "bad id\r\nX-Injected: 1", generated: true},
+		{requestID: strings.Repeat("a", maxRequestIDLength+1), generated: true},
+		{
+			requestID:   "abc-123",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			traceID:     "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	} {
+		var contextID string
+		h := Correlate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contextID = RequestIDFromContext(r.Context())
+			NotFound(w)
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.requestID != "" {
+			r.Header.Set(RequestIDHeader, test.requestID)
+		}
+		if test.traceparent != "" {
+			r.Header.Set("Traceparent", test.traceparent)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		id := w.Header().Get(RequestIDHeader)
+		if test.generated {
+			if len(id) != 32 || !isLowerHex(id) {
+				t.Errorf("expected generated request ID, got %#v", id)
+			}
+		} else if id != test.requestID {
+			t.Errorf("expected %#v, got %#v", test.requestID, id)
+		}
+		if contextID != id {
+			t.Errorf("expected %#v, got %#v", id, contextID)
+		}
+		if traceID := w.Header().Get(TraceIDHeader); traceID != test.traceID {
+			t.Errorf("expected %#v, got %#v", test.traceID, traceID)
+		}
+
+		resp := &Response{}
+		if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if resp.Meta["request_id"] != id {
+			t.Errorf("expected %#v, got %#v", id, resp.Meta["request_id"])
+		}
+		if traceID, _ := resp.Meta["trace_id"].(string); traceID != test.traceID {
+			t.Errorf("expected %#v, got %#v", test.traceID, traceID)
+		}
+	}
+}
+
+func TestCorrelatorCustomHeader(t *testing.T) {
+	c := &Correlator{Header: "X-Correlation-ID", NewID: func() string { return "generated" }}
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	}))
+
+	for _, test := range []struct {
+		header string
+		id     string
+	}{
+		{"from-client", "from-client"},
+		{"", "generated"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.header != "" {
+			r.Header.Set("X-Correlation-ID", test.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if id := w.Header().Get(RequestIDHeader); id != test.id {
+			t.Errorf("expected %#v, got %#v", test.id, id)
+		}
+	}
+}
+
+func TestCorrelateKeepsMeta(t *testing.T) {
+	h := Correlate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(&Redirector{IncludeLocation: true}).SeeOther(w, r, "/users/1")
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Meta["request_id"] != "abc-123" || resp.Meta["location"] != "/users/1" {
+		t.Errorf("expected request_id and location in meta, got %#v", resp.Meta)
+	}
+}
@@ -0,0 +1,91 @@
+package jsonapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Algorithms of the Content-Digest header (RFC 9530).
+const (
+	DigestSHA256 = "sha-256"
+	DigestSHA512 = "sha-512"
+)
+
+// ErrContentDigest is returned when the Content-Digest header of a message
+// is missing, malformed or does not match its content.
+var ErrContentDigest = errors.New("jsonapi: content digest mismatch")
+
+// newDigestHash returns the hash of the Content-Digest algorithm alg.
+func newDigestHash(alg string) (hash.Hash, error) {
+	switch alg {
+	case DigestSHA256:
+		return sha256.New(), nil
+	case DigestSHA512:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("jsonapi: unsupported digest algorithm %q", alg)
+}
+
+// ContentDigest returns the value of the Content-Digest header of a message
+// with the content body, with a digest for each of the algorithms, such as
+// DigestSHA256. Without algorithms, DigestSHA256 is used.
+func ContentDigest(body []byte, algorithms ...string) (string, error) {
+	if len(algorithms) == 0 {
+		algorithms = []string{DigestSHA256}
+	}
+
+	var b strings.Builder
+	for i, alg := range algorithms {
+		h, err := newDigestHash(alg)
+		if err != nil {
+			return "", err
+		}
+		h.Write(body)
+
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(alg)
+		b.WriteString("=:")
+		b.WriteString(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+		b.WriteString(":")
+	}
+	return b.String(), nil
+}
+
+// VerifyContentDigest checks the value of a Content-Digest header against
+// the content body. Every digest with a supported algorithm must match, and
+// there must be at least one.
+func VerifyContentDigest(header string, body []byte) error {
+	digests, err := parseDictionary(header)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrContentDigest, err)
+	}
+
+	var verified int
+	for _, d := range digests {
+		h, err := newDigestHash(d.key)
+		if err != nil {
+			continue
+		}
+		expected, ok := d.byteSequence()
+		if !ok {
+			return fmt.Errorf("%w: %s is not a byte sequence", ErrContentDigest, d.key)
+		}
+		h.Write(body)
+		if !bytes.Equal(h.Sum(nil), expected) {
+			return fmt.Errorf("%w: %s", ErrContentDigest, d.key)
+		}
+		verified++
+	}
+	if verified == 0 {
+		return fmt.Errorf("%w: no supported digest", ErrContentDigest)
+	}
+	return nil
+}
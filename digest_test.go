@@ -0,0 +1,57 @@
+package jsonapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestContentDigest(t *testing.T) {
+	// The examples of RFC 9530, appendix B.
+	body := []byte(`{"hello": "world"}`)
+
+	for _, test := range []struct {
+		algorithms []string
+		expected   string
+	}{
+		{nil, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"},
+		{[]string{DigestSHA512}, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"},
+		{[]string{DigestSHA256, DigestSHA512}, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:, sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"},
+	} {
+		digest, err := ContentDigest(body, test.algorithms...)
+		if err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if digest != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, digest)
+		}
+		if err := VerifyContentDigest(digest, body); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+	}
+
+	if _, err := ContentDigest(body, "md5"); err == nil {
+		t.Errorf("expected an error, got %#v", err)
+	}
+}
+
+func TestVerifyContentDigest(t *testing.T) {
+	body := []byte(`{"hello": "world"}`)
+
+	for _, header := range []string{
+		"",
+		"sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:, sha-512=:AAAA:",
+		"sha-256=:AAAA:",
+		"sha-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=",
+		"md5=:AAAA:",
+		"sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:,",
+	} {
+		if err := VerifyContentDigest(header, body); !errors.Is(err, ErrContentDigest) {
+			t.Errorf("expected %#v for %#v, got %#v", ErrContentDigest, header, err)
+		}
+	}
+
+	// Unsupported algorithms are ignored.
+	if err := VerifyContentDigest("unixsum=:AAAA:, sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", body); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+}
@@ -0,0 +1,139 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which encode buffers are not
+// returned to the pool, so that a few large responses do not pin memory.
+const maxPooledBuffer = 64 << 10
+
+// encodeBuffer is a buffer with a JSON encoder that writes to it.
+type encodeBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		eb := &encodeBuffer{}
+		eb.enc = json.NewEncoder(&eb.Buffer)
+		return eb
+	},
+}
+
+func getEncodeBuffer() *encodeBuffer {
+	return encodeBuffers.Get().(*encodeBuffer)
+}
+
+func putEncodeBuffer(eb *encodeBuffer) {
+	if eb.Cap() > maxPooledBuffer {
+		return
+	}
+	eb.Reset()
+	encodeBuffers.Put(eb)
+}
+
+// encodeValue appends v to eb as JSON, without the newline of the encoder.
+func (eb *encodeBuffer) encodeValue(v interface{}) error {
+	if err := eb.enc.Encode(v); err != nil {
+		return err
+	}
+	eb.Truncate(eb.Len() - 1)
+	return nil
+}
+
+// encode writes the default JSON structure of a response to eb, the same
+// way encoding/json encodes a Response. The envelope is written directly
+// around the encoding of data and meta.
+//
+// Data that is already encoded, as Raw, json.RawMessage or the output of a
+// json.Marshaler, is written verbatim, unless it is empty, in which case it
+// is written as null. Unlike with encoding/json, its HTML characters are not
+// escaped, and it is only validated if j.ValidateRaw is set.
+//
+// If j.Canonical is set, the response is canonicalized, without a trailing
+// newline.
+func (j *JSONResponder) encode(eb *encodeBuffer, code int, data interface{}, meta map[string]interface{}) error {
+	eb.WriteString(`{"code":`)
+	eb.WriteString(strconv.Itoa(code))
+	eb.WriteString(`,"data":`)
+
+	payload, ok, err := rawPayload(data)
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		if err := eb.encodeValue(data); err != nil {
+			return err
+		}
+	case j.ValidateRaw && !json.Valid(payload):
+		return ErrInvalidRaw
+	default:
+		eb.Write(payload)
+	}
+
+	if len(meta) > 0 {
+		eb.WriteString(`,"meta":`)
+		if err := eb.encodeValue(meta); err != nil {
+			return err
+		}
+	}
+	eb.WriteByte('}')
+	return j.finish(eb)
+}
+
+// encodeProblem writes p to eb, without the default JSON structure.
+func (j *JSONResponder) encodeProblem(eb *encodeBuffer, p interface{}) error {
+	if err := eb.encodeValue(p); err != nil {
+		return err
+	}
+	return j.finish(eb)
+}
+
+// finish ends the body in eb with a newline, or canonicalizes it if
+// j.Canonical is set.
+func (j *JSONResponder) finish(eb *encodeBuffer) error {
+	if !j.Canonical {
+		eb.WriteByte('\n')
+		return nil
+	}
+
+	body, err := Canonicalize(eb.Bytes())
+	if err != nil {
+		return err
+	}
+	eb.Reset()
+	eb.Write(body)
+	return nil
+}
+
+// defaultBodies caches the bodies of responses without data or meta, keyed
+// by status code and text, so that changes to the status code registry take
+// effect.
+var defaultBodies sync.Map
+
+type defaultBodyKey struct {
+	code int
+	text string
+}
+
+// defaultBody returns the body of responses with the status code of info
+// and its text as data.
+func defaultBody(info StatusInfo) []byte {
+	key := defaultBodyKey{info.Code, info.Text}
+	if body, ok := defaultBodies.Load(key); ok {
+		return body.([]byte)
+	}
+
+	eb := &encodeBuffer{}
+	eb.enc = json.NewEncoder(&eb.Buffer)
+	if err := (&JSONResponder{}).encode(eb, info.Code, info.Text, nil); err != nil {
+		panic(err)
+	}
+	body, _ := defaultBodies.LoadOrStore(key, eb.Bytes())
+	return body.([]byte)
+}
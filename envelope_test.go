@@ -0,0 +1,84 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestEncodeMatchesResponse(t *testing.T) {
+	for _, test := range []struct {
+		code int
+		data interface{}
+		meta map[string]interface{}
+	}{
+		{http.StatusOK, "hello", nil},
+		{http.StatusOK, nil, nil},
+		{http.StatusBadRequest, "<script>&</script>", nil},
+		{http.StatusOK, map[string]interface{}{"b": 1, "a": []int{1, 2}}, map[string]interface{}{"page": 2}},
+		{http.StatusCreated, &benchmarkUser{ID: 1, Name: "Ada"}, map[string]interface{}{}},
+	} {
+		expected := &bytes.Buffer{}
+		if err := json.NewEncoder(expected).Encode(&Response{Code: test.code, Data: test.data, Meta: test.meta}); err != nil {
+			t.Fatalf("expected %#v, got %#v", nil, err)
+		}
+
+		eb := getEncodeBuffer()
+		if err := (&JSONResponder{}).encode(eb, test.code, test.data, test.meta); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if eb.String() != expected.String() {
+			t.Errorf("expected %#v, got %#v", expected.String(), eb.String())
+		}
+		putEncodeBuffer(eb)
+	}
+}
+
+func TestDefaultBody(t *testing.T) {
+	info, _ := LookupStatus(http.StatusNotFound)
+	if body := string(defaultBody(info)); body != `{"code":404,"data":"Not Found"}`+"\n" {
+		t.Errorf("expected %#v, got %#v", `{"code":404,"data":"Not Found"}`+"\n", body)
+	}
+
+	defer func() {
+		statuses.Lock()
+		delete(statuses.m, 521)
+		statuses.Unlock()
+	}()
+	for _, text := range []string{"Web Server Is Down", "Origin Down"} {
+		RegisterStatus(StatusInfo{Code: 521, Text: text, BodyAllowed: true})
+
+		w := httptest.NewRecorder()
+		Respond(w, 521)
+
+		expected := fmt.Sprintf(`{"code":521,"data":%q}`+"\n", text)
+		if w.Body.String() != expected {
+			t.Errorf("expected %#v, got %#v", expected, w.Body.String())
+		}
+	}
+}
+
+func TestEncodeBufferReuse(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				w := httptest.NewRecorder()
+				OK(w, i*1000+n)
+
+				expected := fmt.Sprintf(`{"code":200,"data":%d}`+"\n", i*1000+n)
+				if w.Body.String() != expected {
+					t.Errorf("expected %#v, got %#v", expected, w.Body.String())
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
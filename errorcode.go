@@ -0,0 +1,262 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrorCode is a stable application error code, such as "user.email_taken",
+// that clients can rely on to handle errors, unlike messages that may change
+// or be localized.
+//
+// An *ErrorCode is an error, which makes it usable as a sentinel:
+// errors.Is reports whether an error returned by With or Wrap has the code.
+type ErrorCode struct {
+	// Code is the error code.
+	Code string `json:"code"`
+
+	// Status is the status code of responses with the error code.
+	Status int `json:"status"`
+
+	// Message is the template of the message of the error. It may refer to
+	// parameters as {name}, and is localized with the Messages of the
+	// JSONResponder, keyed by Code, when it has one.
+	Message string `json:"message"`
+
+	// DocURL is the URL of the documentation of the error code.
+	DocURL string `json:"doc_url,omitempty"`
+
+	// Retryable reports whether a request that failed with the error code
+	// may succeed if it is retried unchanged.
+	Retryable bool `json:"retryable"`
+}
+
+var errorCodes = struct {
+	sync.RWMutex
+	m map[string]*ErrorCode
+}{m: make(map[string]*ErrorCode)}
+
+// RegisterErrorCode adds c to the error code registry and returns it. It is
+// meant to be used in package-level variable declarations:
+//
+//	var ErrEmailTaken = jsonapi.RegisterErrorCode(jsonapi.ErrorCode{
+//		Code:    "user.email_taken",
+//		Status:  http.StatusConflict,
+//		Message: "The email address {email} is already taken",
+//	})
+//
+// RegisterErrorCode panics if c.Code is empty, contains whitespace, or is
+// already registered, or if c.Status is not an error status code, so that
+// mistakes are found when the program starts.
+func RegisterErrorCode(c ErrorCode) *ErrorCode {
+	if c.Code == "" || strings.ContainsRune(c.Code, ' ') || strings.ContainsFunc(c.Code, isControl) {
+		panic(fmt.Sprintf("jsonapi: invalid error code %q", c.Code))
+	}
+	if c.Status < 400 || c.Status > 599 {
+		panic(fmt.Sprintf("jsonapi: error code %q has non-error status code %d", c.Code, c.Status))
+	}
+
+	errorCodes.Lock()
+	defer errorCodes.Unlock()
+
+	if _, ok := errorCodes.m[c.Code]; ok {
+		panic(fmt.Sprintf("jsonapi: duplicate error code %q", c.Code))
+	}
+	ec := &c
+	errorCodes.m[c.Code] = ec
+	return ec
+}
+
+// LookupErrorCode returns the registered error code code.
+func LookupErrorCode(code string) (*ErrorCode, bool) {
+	errorCodes.RLock()
+	defer errorCodes.RUnlock()
+
+	ec, ok := errorCodes.m[code]
+	return ec, ok
+}
+
+// ErrorCodes returns all registered error codes, sorted by code.
+func ErrorCodes() []ErrorCode {
+	errorCodes.RLock()
+	codes := make([]ErrorCode, 0, len(errorCodes.m))
+	for _, ec := range errorCodes.m {
+		codes = append(codes, *ec)
+	}
+	errorCodes.RUnlock()
+
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i].Code < codes[j].Code
+	})
+	return codes
+}
+
+// WriteErrorCodes writes all registered error codes to w as an indented
+// JSON array, for generating the error handling of clients.
+func WriteErrorCodes(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(ErrorCodes())
+}
+
+// ErrorCodesHandler returns a handler that responds with all registered error
+// codes.
+func ErrorCodesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w, ErrorCodes())
+	})
+}
+
+// Error returns the error code.
+func (c *ErrorCode) Error() string {
+	return c.Code
+}
+
+// With returns an error with the error code and the parameters of its
+// message.
+func (c *ErrorCode) With(params map[string]interface{}) *Error {
+	return &Error{Code: c, Params: params}
+}
+
+// Wrap returns an error with the error code that wraps err. err is not
+// written in responses.
+func (c *ErrorCode) Wrap(err error) *Error {
+	return &Error{Code: c, Err: err}
+}
+
+// Error is an error with an application error code. Used as the data of a
+// response, it is written as the code, message, documentation URL and
+// retryability of its error code.
+type Error struct {
+	Code   *ErrorCode
+	Params map[string]interface{}
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the message of the error, followed by the underlying error.
+func (e *Error) Error() string {
+	msg := e.message()
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the error code of e.
+func (e *Error) Is(target error) bool {
+	return target == error(e.Code)
+}
+
+// message returns the message of e in the default language.
+func (e *Error) message() string {
+	return expandTemplate(e.Code.Message, e.Params)
+}
+
+// errorBody is how an Error is written in the default JSON structure.
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	DocURL    string `json:"doc_url,omitempty"`
+	Retryable bool   `json:"retryable,omitempty"`
+}
+
+// MarshalJSON encodes e with its message in the default language. Responses
+// written with a JSONResponder localize the message instead.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.body(e.message()))
+}
+
+func (e *Error) body(message string) errorBody {
+	return errorBody{
+		Code:      e.Code.Code,
+		Message:   message,
+		DocURL:    e.Code.DocURL,
+		Retryable: e.Code.Retryable,
+	}
+}
+
+// localize returns the body of e with its message in the language
+// negotiated for req.
+func (j *JSONResponder) localize(w http.ResponseWriter, req *http.Request, e *Error) errorBody {
+	if message, ok := j.message(w, req, e.Code.Code, e.Params); ok {
+		return e.body(message)
+	}
+	return e.body(e.message())
+}
+
+// codedError returns the *Error that data is or wraps, if data is an error.
+func codedError(data interface{}) (*Error, bool) {
+	err, ok := data.(error)
+	if !ok {
+		return nil, false
+	}
+	return errorOf(err)
+}
+
+// errorOf returns the *Error that err is or wraps, or an *Error with the
+// *ErrorCode that err wraps.
+func errorOf(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) && e != nil && e.Code != nil {
+		return e, true
+	}
+	var c *ErrorCode
+	if errors.As(err, &c) && c != nil {
+		return &Error{Code: c}, true
+	}
+	return nil, false
+}
+
+// RespondError writes err with the status code of its error code. Errors
+// without an error code are written as 500 with the status text, so that
+// internal details are not exposed.
+func RespondError(w http.ResponseWriter, err error) {
+	DefaultResponder.RespondError(w, err)
+}
+
+// RespondError writes err with the status code of its error code. Errors
+// without an error code are written as 500 with the status text, so that
+// internal details are not exposed, and reported as incidents if
+// j.OnIncident is set.
+func (j *JSONResponder) RespondError(w http.ResponseWriter, err error) {
+	e, ok := errorOf(err)
+	if !ok {
+		if j.OnIncident != nil {
+			j.respond(w, http.StatusInternalServerError, nil, err)
+		} else {
+			j.respond(w, http.StatusInternalServerError, nil)
+		}
+		return
+	}
+	j.respond(w, e.Code.Status, nil, e)
+}
+
+// NewProblem returns the problem details of err. The error code of err is
+// written as the "code" extension member, and its documentation URL as the
+// problem type. Errors without an error code are described as 500, without
+// detail.
+func NewProblem(err error) *Problem {
+	e, ok := errorOf(err)
+	if !ok {
+		return &Problem{Status: http.StatusInternalServerError}
+	}
+	return &Problem{
+		Type:   e.Code.DocURL,
+		Status: e.Code.Status,
+		Detail: e.message(),
+		Code:   e.Code.Code,
+	}
+}
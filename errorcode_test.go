@@ -0,0 +1,211 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	errTestEmailTaken = RegisterErrorCode(ErrorCode{
+		Code:    "user.email_taken",
+		Status:  http.StatusConflict,
+		Message: "The email address {email} is already taken",
+		DocURL:  "https://example.com/errors/user.email_taken",
+	})
+	errTestUpstream = RegisterErrorCode(ErrorCode{
+		Code:      "test.upstream_unavailable",
+		Status:    http.StatusServiceUnavailable,
+		Message:   "The upstream service is unavailable",
+		Retryable: true,
+	})
+)
+
+type errorData struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	DocURL    string `json:"doc_url"`
+	Retryable bool   `json:"retryable"`
+}
+
+func TestRegisterErrorCodePanics(t *testing.T) {
+	for _, c := range []ErrorCode{
+		{Code: "user.email_taken", Status: http.StatusConflict},
+		{Code: "", Status: http.StatusBadRequest},
+		{Code: "has space", Status: http.StatusBadRequest},
+		{Code: "test.not_an_error", Status: http.StatusOK},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected RegisterErrorCode to panic for %#v", c)
+				}
+			}()
+			RegisterErrorCode(c)
+		}()
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("creating user: %w", errTestEmailTaken.Wrap(errors.New("unique violation")))
+
+	if !errors.Is(err, errTestEmailTaken) {
+		t.Errorf("expected error to have code %#v", errTestEmailTaken.Code)
+	}
+	if errors.Is(err, errTestUpstream) {
+		t.Errorf("expected error not to have code %#v", errTestUpstream.Code)
+	}
+	if expected := "creating user: The email address {email} is already taken: unique violation"; err.Error() != expected {
+		t.Errorf("expected %#v, got %#v", expected, err.Error())
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		code int
+		data interface{}
+	}{
+		{
+			err:  errTestEmailTaken.With(map[string]interface{}{"email": "a@example.com"}),
+			code: http.StatusConflict,
+			data: errorData{
+				Code:    "user.email_taken",
+				Message: "The email address a@example.com is already taken",
+				DocURL:  "https://example.com/errors/user.email_taken",
+			},
+		},
+		{
+			err:  fmt.Errorf("calling upstream: %w", errTestUpstream),
+			code: http.StatusServiceUnavailable,
+			data: errorData{Code: "test.upstream_unavailable", Message: "The upstream service is unavailable", Retryable: true},
+		},
+		{
+			err:  errors.New("connection refused"),
+			code: http.StatusInternalServerError,
+			data: "Internal Server Error",
+		},
+	} {
+		w := httptest.NewRecorder()
+		RespondError(w, test.err)
+
+		if w.Code != test.code {
+			t.Errorf("expected status code %#v, got %#v", test.code, w.Code)
+		}
+
+		var resp struct{ Data json.RawMessage }
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		data := reflect.New(reflect.TypeOf(test.data))
+		if err := json.Unmarshal(resp.Data, data.Interface()); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if !reflect.DeepEqual(data.Elem().Interface(), test.data) {
+			t.Errorf("expected %#v, got %#v", test.data, data.Elem().Interface())
+		}
+	}
+}
+
+func TestErrorCodeAsData(t *testing.T) {
+	w := httptest.NewRecorder()
+	Conflict(w, errTestEmailTaken)
+
+	var resp struct{ Data errorData }
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data.Code != "user.email_taken" {
+		t.Errorf("expected %#v, got %#v", "user.email_taken", resp.Data.Code)
+	}
+}
+
+func TestWrappedErrorCodeAsData(t *testing.T) {
+	for _, err := range []error{
+		fmt.Errorf("creating user: %w", errTestEmailTaken.With(map[string]interface{}{"email": "ada@example.com"})),
+		fmt.Errorf("creating user: %w", errTestEmailTaken),
+	} {
+		w := httptest.NewRecorder()
+		Conflict(w, err)
+
+		var resp struct{ Data errorData }
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if resp.Data.Code != "user.email_taken" {
+			t.Errorf("expected %#v, got %#v", "user.email_taken", resp.Data.Code)
+		}
+	}
+
+	// Wrapped error codes in 5xx responses are not incidents.
+	var incidents int
+	j := &JSONResponder{OnIncident: func(Incident) { incidents++ }}
+	w := httptest.NewRecorder()
+	j.ServiceUnavailable(w, fmt.Errorf("fetching: %w", errTestUpstream))
+
+	if incidents != 0 || w.Header().Get(IncidentHeader) != "" {
+		t.Errorf("expected no incident, got %#v", incidents)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"test.upstream_unavailable"`) {
+		t.Errorf("expected the error code in %#v", w.Body.String())
+	}
+}
+
+func TestRespondErrorLocalized(t *testing.T) {
+	j := &JSONResponder{Messages: loadTestMessages(t)}
+
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.Header.Set("Accept-Language", "de")
+	w := httptest.NewRecorder()
+	j.RespondError(WithRequest(w, r), errTestEmailTaken.With(map[string]interface{}{"email": "a@example.com"}))
+
+	var resp struct{ Data errorData }
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if expected := "Die E-Mail-Adresse a@example.com ist bereits vergeben"; resp.Data.Message != expected {
+		t.Errorf("expected %#v, got %#v", expected, resp.Data.Message)
+	}
+	if language := w.Header().Get("Content-Language"); language != "de" {
+		t.Errorf("expected %#v, got %#v", "de", language)
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	p := NewProblem(errTestEmailTaken.With(map[string]interface{}{"email": "a@example.com"}))
+
+	expected := &Problem{
+		Type:   "https://example.com/errors/user.email_taken",
+		Status: http.StatusConflict,
+		Detail: "The email address a@example.com is already taken",
+		Code:   "user.email_taken",
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("expected %#v, got %#v", expected, p)
+	}
+
+	if p := NewProblem(errors.New("secret")); p.Status != http.StatusInternalServerError || p.Detail != "" {
+		t.Errorf("expected %#v, got %#v", &Problem{Status: http.StatusInternalServerError}, p)
+	}
+}
+
+func TestWriteErrorCodes(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := WriteErrorCodes(b); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+
+	var codes []ErrorCode
+	if err := json.Unmarshal(b.Bytes(), &codes); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if len(codes) != 2 || codes[0] != *errTestUpstream || codes[1] != *errTestEmailTaken {
+		t.Errorf("expected %#v, got %#v", []ErrorCode{*errTestUpstream, *errTestEmailTaken}, codes)
+	}
+}
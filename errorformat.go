@@ -0,0 +1,168 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// maxFormattedErrors is the number of errors of an error tree that are
+// written by the list and details formatters.
+const maxFormattedErrors = 64
+
+// ErrorFormatter renders an error passed as the data of a response.
+type ErrorFormatter func(err error) interface{}
+
+// ErrorDetailer is implemented by errors that describe themselves with
+// structured details, such as the field that failed validation.
+type ErrorDetailer interface {
+	ErrorDetails() interface{}
+}
+
+// MessageFormatter renders err as its message.
+func MessageFormatter(err error) interface{} {
+	return err.Error()
+}
+
+// ListFormatter renders err as the list of the messages of the errors it
+// joins, as with errors.Join, or of err alone.
+func ListFormatter(err error) interface{} {
+	leaves := joinedErrors(err)
+	messages := make([]string, len(leaves))
+	for i, err := range leaves {
+		messages[i] = err.Error()
+	}
+	return messages
+}
+
+// DetailsFormatter renders err as a list of objects, one for each of the
+// errors it joins, as with errors.Join, or for err alone. Errors that are or
+// wrap an ErrorDetailer are rendered as their details, and other errors as
+// an object with their message.
+func DetailsFormatter(err error) interface{} {
+	leaves := joinedErrors(err)
+	details := make([]interface{}, len(leaves))
+	for i, err := range leaves {
+		details[i] = errorDetails(err)
+	}
+	return details
+}
+
+// DefaultErrorFormatter is the ErrorFormatter used when a JSONResponder has
+// none. It renders an error that joins several errors with DetailsFormatter
+// if any of them has details, and with ListFormatter otherwise. Other errors
+// are rendered as their details if they have any, and as their message
+// otherwise. It is not used for 5xx responses, see JSONResponder.
+func DefaultErrorFormatter(err error) interface{} {
+	leaves := joinedErrors(err)
+	if len(leaves) == 1 {
+		return errorDetailsOrMessage(leaves[0])
+	}
+	for _, leaf := range leaves {
+		var d ErrorDetailer
+		if errors.As(leaf, &d) {
+			return DetailsFormatter(err)
+		}
+	}
+	return ListFormatter(err)
+}
+
+// formatError returns the data that replaces err in a response.
+func (j *JSONResponder) formatError(err error) interface{} {
+	if j.ErrorFormatter != nil {
+		return j.ErrorFormatter(err)
+	}
+	return DefaultErrorFormatter(err)
+}
+
+// errorDetailsOrMessage returns the details of err, or its message.
+func errorDetailsOrMessage(err error) interface{} {
+	var d ErrorDetailer
+	if errors.As(err, &d) {
+		return d.ErrorDetails()
+	}
+	return err.Error()
+}
+
+// isPlainError reports whether data is an error that is written with an
+// ErrorFormatter: an ErrorDetailer, or an error that encodes as an empty JSON
+// object, such as those of errors.New and fmt.Errorf. Errors that implement
+// json.Marshaler or have exported fields keep their encoding.
+func isPlainError(data interface{}) (error, bool) {
+	err, ok := data.(error)
+	if !ok || err == nil {
+		return nil, false
+	}
+	if _, ok := data.(json.Marshaler); ok {
+		return nil, false
+	}
+	if _, ok := data.(ErrorDetailer); ok {
+		return err, true
+	}
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || hasEncodedFields(t) {
+		return nil, false
+	}
+	return err, true
+}
+
+// hasEncodedFields reports whether encoding/json writes any field of the
+// struct type t, including the fields promoted from embedded structs.
+func hasEncodedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("json") == "-" {
+			continue
+		}
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if hasEncodedFields(ft) {
+					return true
+				}
+				continue
+			}
+		}
+		if f.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// errorDetails returns the details of err, or an object with its message.
+func errorDetails(err error) interface{} {
+	var d ErrorDetailer
+	if errors.As(err, &d) {
+		return d.ErrorDetails()
+	}
+	return map[string]interface{}{"message": err.Error()}
+}
+
+// joinedErrors returns the errors joined by err, recursively, or err if it
+// does not join errors. Errors that wrap a single error are not unwrapped,
+// so that their message keeps its context.
+func joinedErrors(err error) []error {
+	var leaves []error
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil || len(leaves) == maxFormattedErrors {
+			return
+		}
+		if u, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range u.Unwrap() {
+				walk(err)
+			}
+			return
+		}
+		leaves = append(leaves, err)
+	}
+	walk(err)
+	return leaves
+}
@@ -0,0 +1,152 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type fieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *fieldError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+func (e *fieldError) ErrorDetails() interface{} {
+	return map[string]interface{}{"field": e.Field, "reason": e.Reason}
+}
+
+type marshalingError struct{}
+
+func (marshalingError) Error() string {
+	return "marshaling error"
+}
+
+func (marshalingError) MarshalJSON() ([]byte, error) {
+	return []byte(`{"custom":true}`), nil
+}
+
+func TestErrorFormatters(t *testing.T) {
+	joined := errors.Join(errors.New("name is required"), fmt.Errorf("age: %w", &fieldError{"age", "must be positive"}))
+
+	for _, test := range []struct {
+		formatter ErrorFormatter
+		err       error
+		data      interface{}
+	}{
+		{MessageFormatter, errors.New("bad input"), "bad input"},
+		{ListFormatter, errors.New("bad input"), []interface{}{"bad input"}},
+		{ListFormatter, joined, []interface{}{"name is required", "age: age must be positive"}},
+		{
+			DetailsFormatter,
+			joined,
+			[]interface{}{
+				map[string]interface{}{"message": "name is required"},
+				map[string]interface{}{"field": "age", "reason": "must be positive"},
+			},
+		},
+		{DefaultErrorFormatter, errors.New("bad input"), "bad input"},
+		{DefaultErrorFormatter, fmt.Errorf("decoding: %w", errors.New("unexpected EOF")), "decoding: unexpected EOF"},
+		{DefaultErrorFormatter, &fieldError{"email", "is invalid"}, map[string]interface{}{"field": "email", "reason": "is invalid"}},
+		{DefaultErrorFormatter, errors.Join(errors.New("a"), errors.Join(errors.New("b"), errors.New("c"))), []interface{}{"a", "b", "c"}},
+		{
+			DefaultErrorFormatter,
+			joined,
+			[]interface{}{
+				map[string]interface{}{"message": "name is required"},
+				map[string]interface{}{"field": "age", "reason": "must be positive"},
+			},
+		},
+	} {
+		w := httptest.NewRecorder()
+		(&JSONResponder{ErrorFormatter: test.formatter}).BadRequest(w, test.err)
+
+		resp := &Response{}
+		if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if !reflect.DeepEqual(resp.Data, test.data) {
+			t.Errorf("expected %#v, got %#v", test.data, resp.Data)
+		}
+	}
+}
+
+func TestRespondPlainError(t *testing.T) {
+	w := httptest.NewRecorder()
+	BadRequest(w, errors.New("bad input"))
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "bad input" {
+		t.Errorf("expected %#v, got %#v", "bad input", resp.Data)
+	}
+}
+
+func TestRespondServerErrorHidesMessage(t *testing.T) {
+	err := errors.New("pq: password authentication failed for user admin")
+
+	for _, test := range []struct {
+		j        *JSONResponder
+		expected string
+	}{
+		{&JSONResponder{}, `{"code":500,"data":"Internal Server Error"}` + "\n"},
+		{&JSONResponder{RevealIncidents: true}, `{"code":500,"data":"pq: password authentication failed for user admin"}` + "\n"},
+		{&JSONResponder{ErrorFormatter: ListFormatter}, `{"code":500,"data":["pq: password authentication failed for user admin"]}` + "\n"},
+	} {
+		w := httptest.NewRecorder()
+		test.j.InternalServerError(w, err)
+
+		if w.Body.String() != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, w.Body.String())
+		}
+	}
+}
+
+func TestRespondMarshalingError(t *testing.T) {
+	w := httptest.NewRecorder()
+	Respond(w, http.StatusBadRequest, marshalingError{})
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	expected := map[string]interface{}{"custom": true}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Errorf("expected %#v, got %#v", expected, resp.Data)
+	}
+}
+
+type validationError struct {
+	Field  string
+	reason string
+}
+
+func (e *validationError) Error() string {
+	return e.Field + " " + e.reason
+}
+
+func TestRespondStructError(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected string
+	}{
+		{&validationError{Field: "email", reason: "is taken"}, `{"code":400,"data":{"Field":"email"}}` + "\n"},
+		{fmt.Errorf("validating: %w", &validationError{Field: "email"}), `{"code":400,"data":"validating: email "}` + "\n"},
+	} {
+		w := httptest.NewRecorder()
+		BadRequest(w, test.err)
+
+		if w.Body.String() != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package jsonapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// IncidentHeader is the response header that carries the incident ID of a
+// safe server error.
+const IncidentHeader = "X-Incident-ID"
+
+// maxIncidentCauses is the number of errors of an error tree that are kept
+// as the causes of an incident.
+const maxIncidentCauses = 32
+
+// Incident describes a server error whose details were withheld from the
+// client. See JSONResponder.OnIncident.
+type Incident struct {
+	// ID is the incident ID sent to the client.
+	ID string
+
+	// Request is the request of a request-aware response, or nil.
+	Request *http.Request
+
+	// Status is the status code of the response.
+	Status int
+
+	// Err is the error that was withheld.
+	Err error
+
+	// Causes are the messages of Err and the errors it wraps, including
+	// those joined with errors.Join, depth first.
+	Causes []string
+
+	// Stack is the stack of the goroutine that wrote the response, if
+	// JSONResponder.IncidentStack is set.
+	Stack []byte
+
+	// CallSite is where the response was written.
+	CallSite CallSite
+}
+
+// String returns the incident ID, call site and error.
+func (i Incident) String() string {
+	return fmt.Sprintf("incident %s: %s: %d response: %v", i.ID, i.CallSite, i.Status, i.Err)
+}
+
+// LogIncident is an OnIncident hook that logs incidents with the log
+// package, with their causes and stack.
+func LogIncident(i Incident) {
+	var b strings.Builder
+	b.WriteString("jsonapi: ")
+	b.WriteString(i.String())
+	for _, cause := range i.Causes[min(1, len(i.Causes)):] {
+		b.WriteString("\n\tcaused by: ")
+		b.WriteString(cause)
+	}
+	if len(i.Stack) > 0 {
+		b.WriteString("\n")
+		b.Write(i.Stack)
+	}
+	log.Print(b.String())
+}
+
+// incident reports err to j.OnIncident and returns the data and meta of the
+// response that replaces it.
+func (j *JSONResponder) incident(w http.ResponseWriter, req *http.Request, info StatusInfo, err error, meta map[string]interface{}) (interface{}, map[string]interface{}) {
+	i := Incident{
+		ID:       randomHex(8),
+		Request:  req,
+		Status:   info.Code,
+		Err:      err,
+		Causes:   errorCauses(err),
+		CallSite: callSite(),
+	}
+	if j.IncidentStack {
+		i.Stack = debug.Stack()
+	}
+	j.OnIncident(i)
+
+	w.Header().Set(IncidentHeader, i.ID)
+
+	m := make(map[string]interface{}, len(meta)+3)
+	for k, v := range meta {
+		m[k] = v
+	}
+	m["incident_id"] = i.ID
+
+	if !j.RevealIncidents {
+		return j.statusText(w, req, info), m
+	}
+	m["causes"] = i.Causes
+	if i.Stack != nil {
+		m["stack"] = string(i.Stack)
+	}
+	return err.Error(), m
+}
+
+// randomHex returns n random bytes as hex digits.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// errorCauses returns the messages of err and the errors it wraps, depth
+// first, up to maxIncidentCauses.
+func errorCauses(err error) []string {
+	var causes []string
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil || len(causes) == maxIncidentCauses {
+			return
+		}
+		causes = append(causes, err.Error())
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, err := range u.Unwrap() {
+				walk(err)
+			}
+		}
+	}
+	walk(err)
+	return causes
+}
@@ -0,0 +1,110 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSafeServerErrors(t *testing.T) {
+	var incidents []Incident
+	j := &JSONResponder{OnIncident: func(i Incident) { incidents = append(incidents, i) }}
+
+	err := fmt.Errorf("loading user: %w", errors.Join(errors.New("dial tcp: connection refused"), errors.New("retry failed")))
+
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	w := httptest.NewRecorder()
+	j.InternalServerError(WithRequest(w, r), err)
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "Internal Server Error" {
+		t.Errorf("expected %#v, got %#v", "Internal Server Error", resp.Data)
+	}
+
+	if len(incidents) != 1 {
+		t.Fatalf("expected %#v incidents, got %#v", 1, len(incidents))
+	}
+	i := incidents[0]
+
+	id := w.Header().Get(IncidentHeader)
+	if id == "" || id != i.ID || resp.Meta["incident_id"] != id {
+		t.Errorf("expected incident ID %#v in header and meta, got %#v and %#v", i.ID, id, resp.Meta["incident_id"])
+	}
+	if i.Request != r || i.Status != http.StatusInternalServerError || i.Err != err {
+		t.Errorf("expected incident of %#v, got %#v", err, i)
+	}
+	if !strings.HasSuffix(i.CallSite.File, "incident_test.go") {
+		t.Errorf("expected call site in %#v, got %#v", "incident_test.go", i.CallSite.File)
+	}
+	if i.Stack != nil {
+		t.Errorf("expected no stack, got %#v", string(i.Stack))
+	}
+
+	causes := []string{
+		err.Error(),
+		"dial tcp: connection refused\nretry failed",
+		"dial tcp: connection refused",
+		"retry failed",
+	}
+	if !reflect.DeepEqual(i.Causes, causes) {
+		t.Errorf("expected %#v, got %#v", causes, i.Causes)
+	}
+}
+
+func TestSafeServerErrorsIgnored(t *testing.T) {
+	called := false
+	j := &JSONResponder{OnIncident: func(i Incident) { called = true }}
+
+	for _, test := range []struct {
+		code int
+		data interface{}
+	}{
+		{http.StatusBadRequest, errors.New("bad input")},
+		{http.StatusInternalServerError, "a string"},
+		{http.StatusServiceUnavailable, errTestUpstream},
+	} {
+		w := httptest.NewRecorder()
+		j.Respond(w, test.code, test.data)
+
+		if w.Header().Get(IncidentHeader) != "" {
+			t.Errorf("expected no incident for %#v", test.data)
+		}
+	}
+	if called {
+		t.Errorf("expected OnIncident not to be called")
+	}
+}
+
+func TestRevealIncidents(t *testing.T) {
+	j := &JSONResponder{OnIncident: func(i Incident) {}, IncidentStack: true, RevealIncidents: true}
+
+	w := httptest.NewRecorder()
+	j.RespondError(w, fmt.Errorf("query: %w", errors.New("syntax error")))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code %#v, got %#v", http.StatusInternalServerError, w.Code)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "query: syntax error" {
+		t.Errorf("expected %#v, got %#v", "query: syntax error", resp.Data)
+	}
+	causes := []interface{}{"query: syntax error", "syntax error"}
+	if !reflect.DeepEqual(resp.Meta["causes"], causes) {
+		t.Errorf("expected %#v, got %#v", causes, resp.Meta["causes"])
+	}
+	if stack, _ := resp.Meta["stack"].(string); !strings.Contains(stack, "TestRevealIncidents") {
+		t.Errorf("expected stack of the test, got %#v", stack)
+	}
+}
@@ -0,0 +1,138 @@
+package jsonapi
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxInterceptedMessage is the longest plain-text error message that is kept
+// when an error response is rewritten.
+const maxInterceptedMessage = 1 << 10
+
+// Interceptor is a middleware that rewrites error responses that are not
+// JSON, such as those written by http.Error, http.FileServer,
+// http.TimeoutHandler or handlers that hit a http.MaxBytesReader limit.
+//
+// Responses with a status code of 400 or above and a Content-Type other than
+// JSON have their body replaced. Plain-text messages are kept as the data of
+// the response; other bodies are replaced with the status text. The
+// status code and headers are kept, except for those describing the
+// original body.
+type Interceptor struct {
+	// Problem writes RFC 9457 problem details instead of the default JSON
+	// structure.
+	Problem bool
+
+	// Responder writes the rewritten responses. It defaults to
+	// DefaultResponder.
+	Responder *JSONResponder
+}
+
+// Intercept returns a handler that rewrites the error responses of next that
+// are not JSON with the default JSON structure.
+func Intercept(next http.Handler) http.Handler {
+	return (&Interceptor{}).Handler(next)
+}
+
+// Handler returns a handler that rewrites the error responses of next that
+// are not JSON.
+func (ic *Interceptor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iw := &interceptWriter{ResponseWriter: w}
+		next.ServeHTTP(iw, r)
+		if iw.intercepted {
+			ic.rewrite(w, r, iw)
+		}
+	})
+}
+
+// rewrite writes the response intercepted by iw as JSON.
+func (ic *Interceptor) rewrite(w http.ResponseWriter, r *http.Request, iw *interceptWriter) {
+	message := StatusText(iw.code)
+	if iw.plainText && iw.body.Len() > 0 && utf8.Valid(iw.body.Bytes()) {
+		message = strings.TrimSpace(iw.body.String())
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+
+	j := responderOf(ic.Responder)
+	if ic.Problem {
+		j.RespondProblem(w, &Problem{Status: iw.code, Detail: message, Instance: r.URL.Path})
+		return
+	}
+	j.Respond(w, iw.code, message)
+}
+
+// interceptWriter is an http.ResponseWriter that holds back error responses
+// that are not JSON.
+type interceptWriter struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+	intercepted bool
+	plainText   bool
+	code        int
+	body        bytes.Buffer
+}
+
+func (iw *interceptWriter) WriteHeader(code int) {
+	if iw.wroteHeader {
+		if !iw.intercepted {
+			iw.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	iw.wroteHeader = true
+	iw.code = code
+
+	contentType := iw.Header().Get("Content-Type")
+	if code >= 400 && !isJSON(contentType) {
+		iw.intercepted = true
+		iw.plainText = strings.HasPrefix(contentType, "text/plain")
+		return
+	}
+	iw.ResponseWriter.WriteHeader(code)
+}
+
+func (iw *interceptWriter) Write(b []byte) (int, error) {
+	if !iw.wroteHeader {
+		iw.WriteHeader(http.StatusOK)
+	}
+	if !iw.intercepted {
+		return iw.ResponseWriter.Write(b)
+	}
+	if n := maxInterceptedMessage - iw.body.Len(); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		iw.body.Write(b[:n])
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher. Intercepted responses are not flushed.
+func (iw *interceptWriter) Flush() {
+	if !iw.intercepted {
+		http.NewResponseController(iw.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for
+// http.ResponseController.
+func (iw *interceptWriter) Unwrap() http.ResponseWriter {
+	return iw.ResponseWriter
+}
+
+// isJSON reports whether contentType is a JSON media type.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,147 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestInterceptRewritesPlainTextErrors(t *testing.T) {
+	h := Intercept(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %#v, got %#v", http.StatusMethodNotAllowed, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=UTF-8" {
+		t.Errorf("expected %#v, got %#v", "application/json; charset=UTF-8", ct)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET" {
+		t.Errorf("expected %#v, got %#v", "GET", allow)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "only GET is supported" {
+		t.Errorf("expected %#v, got %#v", "only GET is supported", resp.Data)
+	}
+}
+
+func TestInterceptFileServer(t *testing.T) {
+	h := Intercept(http.FileServer(http.FS(fstest.MapFS{
+		"hello.txt": {Data: []byte("hello")},
+	})))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing.txt", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status code %#v, got %#v", http.StatusNotFound, w.Code)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("expected %#v, got %#v", http.StatusNotFound, resp.Code)
+	}
+
+	// Successful responses are left alone.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hello.txt", nil))
+	if w.Body.String() != "hello" {
+		t.Errorf("expected %#v, got %#v", "hello", w.Body.String())
+	}
+}
+
+func TestInterceptMaxBytesReader(t *testing.T) {
+	h := Intercept(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, 4)
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		OK(w)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code %#v, got %#v", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "http: request body too large" {
+		t.Errorf("expected %#v, got %#v", "http: request body too large", resp.Data)
+	}
+}
+
+func TestInterceptLeavesJSONErrors(t *testing.T) {
+	h := Intercept(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Conflict(w, "already exists")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "already exists" {
+		t.Errorf("expected %#v, got %#v", "already exists", resp.Data)
+	}
+}
+
+func TestInterceptNonTextBody(t *testing.T) {
+	h := Intercept(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, "<h1>Bad Gateway</h1>")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != http.StatusText(http.StatusBadGateway) {
+		t.Errorf("expected %#v, got %#v", http.StatusText(http.StatusBadGateway), resp.Data)
+	}
+}
+
+func TestInterceptProblem(t *testing.T) {
+	ic := &Interceptor{Problem: true}
+	h := ic.Handler(http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json; charset=UTF-8" {
+		t.Errorf("expected %#v, got %#v", "application/problem+json; charset=UTF-8", ct)
+	}
+	p := &Problem{}
+	if err := json.NewDecoder(w.Body).Decode(p); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	want := Problem{Title: "Not Found", Status: http.StatusNotFound, Detail: "404 page not found", Instance: "/users/7"}
+	if *p != want {
+		t.Errorf("expected %#v, got %#v", want, *p)
+	}
+}
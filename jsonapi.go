@@ -1,7 +1,6 @@
 package jsonapi
 
 import (
-	"encoding/json"
 	"net/http"
 )
 
@@ -83,31 +82,29 @@ type Responder interface {
 
 // Response is the default JSON structure that will be written.
 type Response struct {
-	Code int         `json:"code"`
-	Data interface{} `json:"data"`
+	Code int                    `json:"code"`
+	Data interface{}            `json:"data"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
 }
 
 // respond writes a JSON-encoded body to http.ResponseWriter.
 //
-// he data argument is optional on all methods. If omitted, the response data field
-// will be set to the HTTP status text. If provided, the response data field will be
-// set to the first argument, and all other arguments will be ignored.
+// The data argument is optional on all methods. If omitted, the response data
+// field will be set to the status text from the status code registry. If
+// provided, the response data field will be set to the first argument, and all
+// other arguments will be ignored. Errors given as data are rendered with the
+// ErrorFormatter of the responder, except in 5xx responses, which get the
+// status text by default so that internal details are not revealed.
+// Responses with status codes that do not allow a body, such as 204 and 304,
+// are written without one.
 func respond(w http.ResponseWriter, statusCode int, data ...interface{}) {
-	r := new(Response)
-	r.Code = statusCode
-
-	if len(data) == 0 {
-		r.Data = http.StatusText(statusCode)
-	} else {
-		r.Data = data[0]
-	}
-
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(statusCode)
+	respondMeta(w, statusCode, nil, data...)
+}
 
-	if err := json.NewEncoder(w).Encode(r); err != nil {
-		panic(err)
-	}
+// respondMeta is like respond, but also sets the meta field of the response.
+// The meta field is omitted from the body when meta is empty.
+func respondMeta(w http.ResponseWriter, statusCode int, meta map[string]interface{}, data ...interface{}) {
+	DefaultResponder.respond(w, statusCode, meta, data...)
 }
 
 // Respond writes data with a custom status.
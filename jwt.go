@@ -0,0 +1,316 @@
+package jsonapi
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	_ "crypto/sha256" // Register SHA-256 for crypto.Hash.
+	_ "crypto/sha512" // Register SHA-384 and SHA-512 for crypto.Hash.
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTKey is a key that JSON Web Token signatures are verified with.
+type JWTKey struct {
+	// ID is matched against the "kid" header of tokens. Tokens without a
+	// "kid" header are verified with every key of their algorithm.
+	ID string
+
+	// Algorithm is the "alg" the key verifies: HS256, HS384 or HS512 with a
+	// []byte secret, EdDSA with an ed25519.PublicKey, or ES256, ES384 or
+	// ES512 with an *ecdsa.PublicKey. Tokens signed with another algorithm
+	// are never verified with the key.
+	Algorithm string
+
+	// Key is the secret or public key.
+	Key interface{}
+}
+
+// JWTVerifier verifies JSON Web Tokens signed with HMAC, Ed25519 or ECDSA
+// keys, and checks their registered claims.
+//
+// Keys can be rotated by adding the new key to Keys before tokens are
+// signed with it, and removing the old key once its tokens have expired.
+type JWTVerifier struct {
+	// Keys is the key set tokens are verified with.
+	Keys []JWTKey
+
+	// Issuer, if set, must match the "iss" claim.
+	Issuer string
+
+	// Audience, if set, must be listed in the "aud" claim.
+	Audience string
+
+	// Leeway is the clock skew tolerated when checking the "exp" and "nbf"
+	// claims.
+	Leeway time.Duration
+}
+
+// Claims are the claims of a verified JSON Web Token.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+
+	// Raw holds all claims of the token, including the registered ones.
+	Raw map[string]interface{}
+}
+
+// Verify verifies the signature and claims of token. Errors are
+// *AuthError values with code "invalid_token" that describe why the token
+// was rejected.
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, InvalidToken("malformed token")
+	}
+
+	var header struct {
+		Algorithm string   `json:"alg"`
+		KeyID     string   `json:"kid"`
+		Critical  []string `json:"crit"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, InvalidToken("malformed token header")
+	}
+	// No header extensions are supported, so tokens that require any must
+	// be rejected (RFC 7515, section 4.1.11).
+	if header.Critical != nil {
+		return nil, InvalidToken("unsupported critical header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, InvalidToken("malformed token signature")
+	}
+
+	if _, ok := jwtHashes[header.Algorithm]; !ok {
+		return nil, InvalidToken("unsupported algorithm " + quote(header.Algorithm))
+	}
+
+	keys := v.keys(header.Algorithm, header.KeyID)
+	if len(keys) == 0 {
+		return nil, InvalidToken("unknown signing key")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verifySignature(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, InvalidToken("invalid signature")
+	}
+
+	claims := new(Claims)
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil || claims.Raw == nil {
+		return nil, InvalidToken("malformed token claims")
+	}
+	if err := claims.parse(); err != nil {
+		return nil, err
+	}
+	if err := v.check(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerifyBearer verifies token and returns its claims. It can be used as the
+// Bearer verifier of an Authenticator.
+func (v *JWTVerifier) VerifyBearer(r *http.Request, token string) (interface{}, error) {
+	claims, err := v.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Handler returns a handler that requires requests to next to carry a
+// bearer token verified by v. The claims of the token are stored as the
+// principal of the request.
+func (v *JWTVerifier) Handler(next http.Handler) http.Handler {
+	a := &Authenticator{Bearer: v.VerifyBearer}
+	return a.Handler(next)
+}
+
+// keys returns the keys a token with the given algorithm and key ID may be
+// verified with.
+func (v *JWTVerifier) keys(alg, kid string) []JWTKey {
+	var keys []JWTKey
+	for _, key := range v.Keys {
+		if key.Algorithm != alg {
+			continue
+		}
+		if kid != "" && key.ID != kid {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// check checks the registered claims against v.
+func (v *JWTVerifier) check(c *Claims) error {
+	now := timeNow()
+
+	if !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt.Add(v.Leeway)) {
+		return InvalidToken("token expired")
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.Leeway).Before(c.NotBefore) {
+		return InvalidToken("token not yet valid")
+	}
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return InvalidToken("invalid issuer")
+	}
+	if v.Audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.Audience {
+				return nil
+			}
+		}
+		return InvalidToken("invalid audience")
+	}
+	return nil
+}
+
+// parse sets the registered claims from c.Raw.
+func (c *Claims) parse() error {
+	var ok bool
+	for name, value := range c.Raw {
+		switch name {
+		case "iss":
+			c.Issuer, ok = value.(string)
+		case "sub":
+			c.Subject, ok = value.(string)
+		case "jti":
+			c.ID, ok = value.(string)
+		case "aud":
+			c.Audience, ok = parseAudience(value)
+		case "exp":
+			c.ExpiresAt, ok = parseNumericDate(value)
+		case "nbf":
+			c.NotBefore, ok = parseNumericDate(value)
+		case "iat":
+			c.IssuedAt, ok = parseNumericDate(value)
+		default:
+			continue
+		}
+		if !ok {
+			return InvalidToken("malformed " + quote(name) + " claim")
+		}
+	}
+	return nil
+}
+
+func parseAudience(value interface{}) ([]string, bool) {
+	switch value := value.(type) {
+	case string:
+		return []string{value}, true
+	case []interface{}:
+		aud := make([]string, len(value))
+		for i, v := range value {
+			s, ok := v.(string)
+			if !ok {
+				return nil, false
+			}
+			aud[i] = s
+		}
+		return aud, true
+	}
+	return nil, false
+}
+
+// maxNumericDate is the last second of year 9999, beyond which numeric
+// dates are rejected as malformed.
+const maxNumericDate = 253402300799
+
+func parseNumericDate(value interface{}) (time.Time, bool) {
+	n, ok := value.(float64)
+	if !ok || math.IsNaN(n) || n < 0 || n > maxNumericDate {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
+// decodeSegment decodes a base64url-encoded JSON token segment into v.
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// jwtHashes maps the supported algorithms to their hash function. EdDSA
+// hashes internally, so its hash is 0.
+var jwtHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256,
+	"HS384": crypto.SHA384,
+	"HS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+	"EdDSA": 0,
+}
+
+// jwtCurves maps the ECDSA algorithms to the curve they require.
+var jwtCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+// verifySignature reports whether signature is a valid signature of signed
+// with key.
+func verifySignature(key JWTKey, signed, signature []byte) bool {
+	hash := jwtHashes[key.Algorithm]
+
+	switch k := key.Key.(type) {
+	case []byte:
+		if !strings.HasPrefix(key.Algorithm, "HS") {
+			return false
+		}
+		mac := hmac.New(hash.New, k)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+
+	case ed25519.PublicKey:
+		if key.Algorithm != "EdDSA" || len(k) != ed25519.PublicKeySize {
+			return false
+		}
+		return ed25519.Verify(k, signed, signature)
+
+	case *ecdsa.PublicKey:
+		curve, ok := jwtCurves[key.Algorithm]
+		if !ok || k.Curve != curve {
+			return false
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		h := hash.New()
+		h.Write(signed)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(k, h.Sum(nil), r, s)
+	}
+
+	return false
+}
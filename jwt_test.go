@@ -0,0 +1,242 @@
+package jsonapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signJWT returns a token for claims signed with key.
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(jwtHashes[alg].New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signed))
+	case *ecdsa.PrivateKey:
+		hash := jwtHashes[alg].New()
+		hash.Write([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, k, hash.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	default:
+		t.Fatalf("unsupported key %T", key)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifierAlgorithms(t *testing.T) {
+	fixClock(t, time.Unix(1500000000, 0))
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	ec256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ec521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+
+	v := &JWTVerifier{Keys: []JWTKey{
+		{Algorithm: "HS256", Key: secret},
+		{Algorithm: "HS512", Key: secret},
+		{Algorithm: "EdDSA", Key: edPub},
+		{Algorithm: "ES256", Key: &ec256.PublicKey},
+		{Algorithm: "ES384", Key: &ec384.PublicKey},
+		{Algorithm: "ES512", Key: &ec521.PublicKey},
+	}}
+
+	for _, test := range []struct {
+		alg string
+		key interface{}
+	}{
+		{alg: "HS256", key: secret},
+		{alg: "HS512", key: secret},
+		{alg: "EdDSA", key: edPriv},
+		{alg: "ES256", key: ec256},
+		{alg: "ES384", key: ec384},
+		{alg: "ES512", key: ec521},
+	} {
+		token := signJWT(t, test.alg, "", test.key, map[string]interface{}{"sub": "alice", "exp": 1500000060})
+
+		claims, err := v.Verify(token)
+		if err != nil {
+			t.Errorf("%s: expected %#v, got %#v", test.alg, nil, err)
+			continue
+		}
+		if claims.Subject != "alice" {
+			t.Errorf("%s: expected %#v, got %#v", test.alg, "alice", claims.Subject)
+		}
+		if !claims.ExpiresAt.Equal(time.Unix(1500000060, 0)) {
+			t.Errorf("%s: expected %#v, got %#v", test.alg, time.Unix(1500000060, 0), claims.ExpiresAt)
+		}
+	}
+}
+
+func TestJWTVerifierRejectsTokens(t *testing.T) {
+	fixClock(t, time.Unix(1500000000, 0))
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	v := &JWTVerifier{
+		Keys:     []JWTKey{{ID: "2018", Algorithm: "HS256", Key: secret}},
+		Issuer:   "https://auth.example.com",
+		Audience: "api",
+		Leeway:   30 * time.Second,
+	}
+	valid := map[string]interface{}{"iss": "https://auth.example.com", "aud": []string{"web", "api"}}
+
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[name] = value
+		return claims
+	}
+
+	for _, test := range []struct {
+		token  string
+		reason string
+	}{
+		{token: "abc", reason: "malformed token"},
+		{token: "e30.e30.!!!", reason: "malformed token signature"},
+		{token: signJWT(t, "HS256", "2017", secret, valid), reason: "unknown signing key"},
+		{token: signJWT(t, "HS256", "2018", []byte("wrong"), valid), reason: "invalid signature"},
+		{token: signJWT(t, "EdDSA", "2018", edPriv, valid), reason: "unknown signing key"},
+		{token: signJWT(t, "HS256", "2018", secret, with("exp", 1499999960)), reason: "token expired"},
+		{token: signJWT(t, "HS256", "2018", secret, with("nbf", 1500000040)), reason: "token not yet valid"},
+		{token: signJWT(t, "HS256", "2018", secret, with("iss", "https://evil.com")), reason: "invalid issuer"},
+		{token: signJWT(t, "HS256", "2018", secret, with("aud", "web")), reason: "invalid audience"},
+		{token: signJWT(t, "HS256", "2018", secret, with("exp", "tomorrow")), reason: `malformed "exp" claim`},
+		{token: signJWT(t, "HS256", "2018", secret, with("exp", 1e12)), reason: `malformed "exp" claim`},
+		{token: signJWT(t, "HS256", "2018", secret, with("nbf", 1e12)), reason: `malformed "nbf" claim`},
+		{token: signJWT(t, "HS256", "2018", secret, with("nbf", -1e12)), reason: `malformed "nbf" claim`},
+	} {
+		_, err := v.Verify(test.token)
+
+		authErr := &AuthError{}
+		if !errors.As(err, &authErr) || !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%#v: expected invalid token error, got %#v", test.reason, err)
+			continue
+		}
+		if authErr.Description != test.reason {
+			t.Errorf("expected %#v, got %#v", test.reason, authErr.Description)
+		}
+	}
+
+	// Expiry and not-before within the leeway are accepted.
+	for _, claims := range []map[string]interface{}{with("exp", 1499999980), with("nbf", 1500000020)} {
+		if _, err := v.Verify(signJWT(t, "HS256", "2018", secret, claims)); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+	}
+}
+
+func TestJWTVerifierRejectsNoneAlgorithm(t *testing.T) {
+	v := &JWTVerifier{Keys: []JWTKey{{Algorithm: "HS256", Key: []byte("secret")}}}
+
+	token := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`)) + "."
+
+	if _, err := v.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected invalid token error, got %#v", err)
+	}
+}
+
+func TestJWTVerifierRejectsCriticalHeader(t *testing.T) {
+	key := []byte("secret")
+	v := &JWTVerifier{Keys: []JWTKey{{Algorithm: "HS256", Key: key}}}
+
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","crit":["exp"],"exp":1}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`))
+	mac := hmac.New(jwtHashes["HS256"].New, key)
+	mac.Write([]byte(signed))
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	_, err := v.Verify(token)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Description != "unsupported critical header" {
+		t.Errorf("expected %#v, got %#v", InvalidToken("unsupported critical header"), err)
+	}
+}
+
+func TestJWTVerifierKeyRotation(t *testing.T) {
+	oldKey := []byte("old secret")
+	newKey := []byte("new secret")
+	v := &JWTVerifier{Keys: []JWTKey{
+		{ID: "old", Algorithm: "HS256", Key: oldKey},
+		{ID: "new", Algorithm: "HS256", Key: newKey},
+	}}
+
+	for _, test := range []struct {
+		kid string
+		key []byte
+		ok  bool
+	}{
+		{kid: "old", key: oldKey, ok: true},
+		{kid: "new", key: newKey, ok: true},
+		{kid: "", key: newKey, ok: true},
+		{kid: "old", key: newKey, ok: false},
+	} {
+		_, err := v.Verify(signJWT(t, "HS256", test.kid, test.key, map[string]interface{}{}))
+		if test.ok != (err == nil) {
+			t.Errorf("kid %#v: expected ok %#v, got %#v", test.kid, test.ok, err)
+		}
+	}
+}
+
+func TestJWTVerifierHandler(t *testing.T) {
+	secret := []byte("secret")
+	v := &JWTVerifier{Keys: []JWTKey{{Algorithm: "HS384", Key: secret}}}
+	h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		OK(w, principal.(*Claims).Subject)
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signJWT(t, "HS384", "", secret, map[string]interface{}{"sub": "alice"}))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %#v, got %#v", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+signJWT(t, "HS384", "", []byte("wrong"), map[string]interface{}{"sub": "alice"}))
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code %#v, got %#v", http.StatusUnauthorized, w.Code)
+	}
+	challenge := `Bearer realm="api", error="invalid_token", error_description="invalid signature"`
+	if c := w.Header().Get("WWW-Authenticate"); c != challenge {
+		t.Errorf("expected %#v, got %#v", challenge, c)
+	}
+}
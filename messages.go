@@ -0,0 +1,253 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxAcceptLanguages is the number of language ranges of an Accept-Language
+// header that are considered.
+const maxAcceptLanguages = 16
+
+// Messages is a catalog of localized message templates, keyed by status code,
+// such as "404", or by application error code, such as "user.email_taken".
+//
+// Templates may refer to named parameters as {name}. There is no support for
+// plurals: write messages that read well for any count.
+//
+// A Messages is safe for concurrent use.
+type Messages struct {
+	// Default is the language used when none of the languages accepted by
+	// the client has a message. If empty, "en" is used.
+	Default string
+
+	mu sync.RWMutex
+	m  map[string]map[string]string
+
+	// tags maps normalized languages to the tags they were added with.
+	tags map[string]string
+}
+
+// NewMessages returns an empty catalog with the default language lang.
+func NewMessages(lang string) *Messages {
+	return &Messages{Default: lang}
+}
+
+// Add adds the message templates of lang to the catalog, replacing existing
+// templates with the same keys.
+func (m *Messages) Add(lang string, templates map[string]string) {
+	tag := strings.TrimSpace(lang)
+	lang = normalizeLanguage(lang)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.m == nil {
+		m.m = make(map[string]map[string]string)
+		m.tags = make(map[string]string)
+	}
+	m.tags[lang] = tag
+	if m.m[lang] == nil {
+		m.m[lang] = make(map[string]string, len(templates))
+	}
+	for key, template := range templates {
+		m.m[lang][key] = template
+	}
+}
+
+// Load adds the message templates of the JSON files in the directory dir of
+// fsys, typically an embed.FS. Each file is named after its language, such as
+// "de.json" or "pt-BR.json", and holds an object of keys to templates:
+//
+//	{"404": "Nicht gefunden", "user.email_taken": "{email} ist bereits vergeben"}
+func (m *Messages) Load(fsys fs.FS, dir string) error {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		var templates map[string]string
+		if err := json.Unmarshal(b, &templates); err != nil {
+			return fmt.Errorf("jsonapi: loading messages from %s: %w", name, err)
+		}
+		m.Add(strings.TrimSuffix(path.Base(name), ".json"), templates)
+	}
+	return nil
+}
+
+// Lookup returns the template of key in the first of langs that has one,
+// and that language. Each language falls back to its less specific
+// languages, such as "de-AT" to "de", before the next language is tried,
+// and the default language is tried last. The language is returned as the
+// tag it was added with.
+func (m *Messages) Lookup(langs []string, key string) (template, lang string, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, lang := range m.fallbacks(langs) {
+		if template, ok := m.m[lang][key]; ok {
+			return template, m.tags[lang], true
+		}
+	}
+	return "", "", false
+}
+
+// Format returns the message of key in the language negotiated from the
+// Accept-Language header of r, with the parameters in params filled in. r
+// may be nil, in which case the default language is used.
+func (m *Messages) Format(r *http.Request, key string, params map[string]interface{}) (string, bool) {
+	message, _, ok := m.format(r, key, params)
+	return message, ok
+}
+
+// format is Format, that also returns the language of the message.
+func (m *Messages) format(r *http.Request, key string, params map[string]interface{}) (message, lang string, ok bool) {
+	var langs []string
+	if r != nil {
+		langs = AcceptedLanguages(r)
+	}
+	template, lang, ok := m.Lookup(langs, key)
+	if !ok {
+		return "", "", false
+	}
+	return expandTemplate(template, params), lang, true
+}
+
+// message returns the message of key for a response to req, which may be
+// nil, from j.Messages, and sets the headers that describe its language.
+func (j *JSONResponder) message(w http.ResponseWriter, req *http.Request, key string, params map[string]interface{}) (string, bool) {
+	if j.Messages == nil {
+		return "", false
+	}
+	if req != nil {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	message, lang, ok := j.Messages.format(req, key, params)
+	if ok {
+		w.Header().Set("Content-Language", lang)
+	}
+	return message, ok
+}
+
+// fallbacks returns the languages to try for langs, in order, without
+// duplicates.
+func (m *Messages) fallbacks(langs []string) []string {
+	def := m.Default
+	if def == "" {
+		def = "en"
+	}
+
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(lang string) {
+		for lang = normalizeLanguage(lang); lang != ""; {
+			if !seen[lang] {
+				seen[lang] = true
+				chain = append(chain, lang)
+			}
+			i := strings.LastIndexByte(lang, '-')
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	for _, lang := range langs {
+		add(lang)
+	}
+	add(def)
+	return chain
+}
+
+// AcceptedLanguages returns the languages of the Accept-Language header of r,
+// most preferred first. The wildcard and languages with a weight of 0 are
+// left out.
+func AcceptedLanguages(r *http.Request) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var accepted []weighted
+
+	for _, header := range r.Header.Values("Accept-Language") {
+		for _, part := range strings.Split(header, ",") {
+			if len(accepted) == maxAcceptLanguages {
+				break
+			}
+			lang, params, _ := strings.Cut(part, ";")
+			lang = strings.TrimSpace(lang)
+			if lang == "" || lang == "*" {
+				continue
+			}
+
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					continue
+				}
+				q = f
+			}
+			if q > 0 {
+				accepted = append(accepted, weighted{lang, q})
+			}
+		}
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+
+	langs := make([]string, len(accepted))
+	for i, a := range accepted {
+		langs[i] = a.lang
+	}
+	return langs
+}
+
+// normalizeLanguage returns lang in lower case, with "-" as separator.
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// expandTemplate replaces each {name} in template with the value of name in
+// params. Names that are not in params are left as they are.
+func expandTemplate(template string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(template, "{") {
+		return template
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+
+		b.WriteString(template[:start])
+		if v, ok := params[template[start+1:end]]; ok {
+			fmt.Fprint(&b, v)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
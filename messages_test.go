@@ -0,0 +1,145 @@
+package jsonapi
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/messages/*.json
+var testMessages embed.FS
+
+func loadTestMessages(t *testing.T) *Messages {
+	m := NewMessages("en")
+	if err := m.Load(testMessages, "testdata/messages"); err != nil {
+		t.Fatalf("expected %#v, got %#v", nil, err)
+	}
+	return m
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		langs  []string
+	}{
+		{"", []string{}},
+		{"de", []string{"de"}},
+		{"fr;q=0.5, de-AT, en;q=0.8", []string{"de-AT", "en", "fr"}},
+		{"*, de;q=0, fr;q=0.1", []string{"fr"}},
+		{"de;q=nope, fr", []string{"fr"}},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.header != "" {
+			r.Header.Set("Accept-Language", test.header)
+		}
+
+		if langs := AcceptedLanguages(r); !reflect.DeepEqual(langs, test.langs) {
+			t.Errorf("expected %#v, got %#v", test.langs, langs)
+		}
+	}
+}
+
+func TestMessagesLookup(t *testing.T) {
+	m := loadTestMessages(t)
+
+	for _, test := range []struct {
+		langs    []string
+		key      string
+		template string
+		lang     string
+		ok       bool
+	}{
+		{[]string{"de-AT"}, "404", "Die Ressource wurde leider nicht gefunden", "de-AT", true},
+		{[]string{"de-CH"}, "404", "Die Ressource wurde nicht gefunden", "de", true},
+		{[]string{"de-AT"}, "user.email_taken", "Die E-Mail-Adresse {email} ist bereits vergeben", "de", true},
+		{[]string{"es", "fr"}, "404", "La ressource est introuvable", "fr", true},
+		{[]string{"fr"}, "user.email_taken", "The email address {email} is already taken", "en", true},
+		{nil, "404", "The resource could not be found", "en", true},
+		{[]string{"de"}, "500", "", "", false},
+	} {
+		template, lang, ok := m.Lookup(test.langs, test.key)
+		if template != test.template || lang != test.lang || ok != test.ok {
+			t.Errorf("expected %#v %#v %#v, got %#v %#v %#v", test.template, test.lang, test.ok, template, lang, ok)
+		}
+	}
+}
+
+func TestMessagesFormat(t *testing.T) {
+	m := loadTestMessages(t)
+
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+
+	message, ok := m.Format(r, "user.email_taken", map[string]interface{}{"email": "a@example.com"})
+	if expected := "Die E-Mail-Adresse a@example.com ist bereits vergeben"; !ok || message != expected {
+		t.Errorf("expected %#v, got %#v", expected, message)
+	}
+}
+
+func TestMessagesLoadInvalidJSON(t *testing.T) {
+	fsys := fstest.MapFS{"messages/de.json": {Data: []byte(`["nope"]`)}}
+
+	if err := NewMessages("en").Load(fsys, "messages"); err == nil {
+		t.Errorf("expected an error, got %#v", err)
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	params := map[string]interface{}{"name": "Ada", "count": 3}
+
+	for _, test := range []struct {
+		template string
+		expected string
+	}{
+		{"Hello", "Hello"},
+		{"Hello {name}", "Hello Ada"},
+		{"{name} has {count} items", "Ada has 3 items"},
+		{"{missing} and {name}", "{missing} and Ada"},
+		{"unclosed {name", "unclosed {name"},
+	} {
+		if result := expandTemplate(test.template, params); result != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, result)
+		}
+	}
+}
+
+func TestJSONResponderMessages(t *testing.T) {
+	j := &JSONResponder{Messages: loadTestMessages(t)}
+
+	for _, test := range []struct {
+		header   string
+		code     int
+		data     interface{}
+		language string
+	}{
+		{"de-AT", http.StatusNotFound, "Die Ressource wurde leider nicht gefunden", "de-AT"},
+		{"fr, de;q=0.5", http.StatusNotFound, "La ressource est introuvable", "fr"},
+		{"", http.StatusNotFound, "The resource could not be found", "en"},
+		{"de", http.StatusInternalServerError, "Internal Server Error", ""},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.header != "" {
+			r.Header.Set("Accept-Language", test.header)
+		}
+		w := httptest.NewRecorder()
+		j.Respond(WithRequest(w, r), test.code)
+
+		resp := &Response{}
+		if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if resp.Data != test.data {
+			t.Errorf("expected %#v, got %#v", test.data, resp.Data)
+		}
+		if language := w.Header().Get("Content-Language"); language != test.language {
+			t.Errorf("expected %#v, got %#v", test.language, language)
+		}
+		if vary := w.Header().Get("Vary"); vary != "Accept-Language" {
+			t.Errorf("expected %#v, got %#v", "Accept-Language", vary)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package jsonapi
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default histogram buckets of Metrics.
+var (
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DefaultSizeBuckets     = []float64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+)
+
+// Metrics is an Observer that counts responses by route, method and status
+// code, and records histograms of their latency and size. It exposes them in
+// the Prometheus text exposition format and with expvar, without depending
+// on a metrics library.
+//
+// As an Observer on its own, Metrics records the time spent encoding each
+// response as its latency. Wrap handlers with Handler to record the time
+// since the request was received instead.
+//
+// The zero value is ready to use. A Metrics must not be copied after first
+// use.
+type Metrics struct {
+	// Namespace is the prefix of the metric names. If empty, "jsonapi" is
+	// used.
+	Namespace string
+
+	// DurationBuckets are the upper bounds of the latency histogram, in
+	// seconds. If nil, DefaultDurationBuckets is used.
+	DurationBuckets []float64
+
+	// SizeBuckets are the upper bounds of the size histogram, in bytes. If
+	// nil, DefaultSizeBuckets is used.
+	SizeBuckets []float64
+
+	mu        sync.Mutex
+	responses map[responseSeries]uint64
+	durations map[histogramSeries]*histogram
+	sizes     map[histogramSeries]*histogram
+}
+
+// responseSeries are the labels of the response counter.
+type responseSeries struct {
+	Route, Method, Status, Class string
+}
+
+// histogramSeries are the labels of the latency and size histograms.
+type histogramSeries struct {
+	Route, Method, Class string
+}
+
+type histogram struct {
+	bounds []float64
+	counts []uint64 // counts[i] counts values in (bounds[i-1], bounds[i]].
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)]++
+	h.sum += v
+	h.count++
+}
+
+type metricsStartKey struct{}
+
+// Handler returns a handler that makes the responses written by next
+// request-aware, so that their latency is measured from the time the
+// request was received. See WithRequest.
+func (m *Metrics) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), metricsStartKey{}, time.Now()))
+		next.ServeHTTP(WithRequest(w, r), r)
+	})
+}
+
+// ObserveResponse implements Observer.
+func (m *Metrics) ObserveResponse(o Observation) {
+	var method string
+	latency := o.EncodeDuration
+	if o.Request != nil {
+		method = metricsMethod(o.Request.Method)
+		if start, ok := o.Request.Context().Value(metricsStartKey{}).(time.Time); ok {
+			latency = time.Since(start)
+		}
+	}
+	class := strconv.Itoa(o.Status/100) + "xx"
+	hs := histogramSeries{Route: o.Pattern, Method: method, Class: class}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.responses == nil {
+		m.responses = make(map[responseSeries]uint64)
+		m.durations = make(map[histogramSeries]*histogram)
+		m.sizes = make(map[histogramSeries]*histogram)
+	}
+	m.responses[responseSeries{Route: o.Pattern, Method: method, Status: strconv.Itoa(o.Status), Class: class}]++
+
+	d := m.durations[hs]
+	if d == nil {
+		d = newHistogram(orDefault(m.DurationBuckets, DefaultDurationBuckets))
+		m.durations[hs] = d
+	}
+	d.observe(latency.Seconds())
+
+	s := m.sizes[hs]
+	if s == nil {
+		s = newHistogram(orDefault(m.SizeBuckets, DefaultSizeBuckets))
+		m.sizes[hs] = s
+	}
+	s.observe(float64(o.Bytes))
+}
+
+// PrometheusHandler returns a handler that writes the metrics in the
+// Prometheus text exposition format.
+func (m *Metrics) PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition
+// format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	ns := m.Namespace
+	if ns == "" {
+		ns = "jsonapi"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	name := ns + "_responses_total"
+	fmt.Fprintf(&b, "# HELP %s Responses written, by route, method and status code.\n# TYPE %s counter\n", name, name)
+	responses := make([]responseSeries, 0, len(m.responses))
+	for s := range m.responses {
+		responses = append(responses, s)
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		a, c := responses[i], responses[j]
+		if a.Route != c.Route {
+			return a.Route < c.Route
+		}
+		if a.Method != c.Method {
+			return a.Method < c.Method
+		}
+		return a.Status < c.Status
+	})
+	for _, s := range responses {
+		fmt.Fprintf(&b, "%s{route=%s,method=%s,status=%s,class=%s} %d\n",
+			name, labelValue(s.Route), labelValue(s.Method), labelValue(s.Status), labelValue(s.Class), m.responses[s])
+	}
+
+	writeHistograms(&b, ns+"_response_duration_seconds", "Latency of responses in seconds, by route, method and status class.", m.durations)
+	writeHistograms(&b, ns+"_response_size_bytes", "Size of response bodies in bytes, by route, method and status class.", m.sizes)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHistograms writes the histograms hs named name to b.
+func writeHistograms(b *strings.Builder, name, help string, hs map[histogramSeries]*histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, s := range sortedHistogramSeries(hs) {
+		h := hs[s]
+		labels := fmt.Sprintf("route=%s,method=%s,class=%s", labelValue(s.Route), labelValue(s.Method), labelValue(s.Class))
+
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "%s_bucket{%s,le=%q} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+// Publish publishes the metrics with expvar under name. Like
+// expvar.Publish, it panics if name is already in use.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(m.snapshot))
+}
+
+// snapshot returns the metrics as JSON-encodable values, for expvar.
+func (m *Metrics) snapshot() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	responses := make([]map[string]interface{}, 0, len(m.responses))
+	for s, n := range m.responses {
+		responses = append(responses, map[string]interface{}{
+			"route": s.Route, "method": s.Method, "status": s.Status, "class": s.Class, "count": n,
+		})
+	}
+	sort.Slice(responses, func(i, j int) bool {
+		return fmt.Sprint(responses[i]) < fmt.Sprint(responses[j])
+	})
+
+	return map[string]interface{}{
+		"responses":        responses,
+		"duration_seconds": histogramSnapshot(m.durations),
+		"size_bytes":       histogramSnapshot(m.sizes),
+	}
+}
+
+func histogramSnapshot(hs map[histogramSeries]*histogram) []map[string]interface{} {
+	snapshot := make([]map[string]interface{}, 0, len(hs))
+	for _, s := range sortedHistogramSeries(hs) {
+		h := hs[s]
+		buckets := make(map[string]uint64, len(h.bounds)+1)
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += h.counts[i]
+			buckets[strconv.FormatFloat(bound, 'g', -1, 64)] = cumulative
+		}
+		buckets["+Inf"] = h.count
+		snapshot = append(snapshot, map[string]interface{}{
+			"route": s.Route, "method": s.Method, "class": s.Class,
+			"buckets": buckets, "sum": h.sum, "count": h.count,
+		})
+	}
+	return snapshot
+}
+
+func sortedHistogramSeries(hs map[histogramSeries]*histogram) []histogramSeries {
+	series := make([]histogramSeries, 0, len(hs))
+	for s := range hs {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		a, c := series[i], series[j]
+		if a.Route != c.Route {
+			return a.Route < c.Route
+		}
+		if a.Method != c.Method {
+			return a.Method < c.Method
+		}
+		return a.Class < c.Class
+	})
+	return series
+}
+
+// metricsMethod returns method, or "OTHER" for nonstandard methods, so that
+// clients cannot create series at will.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// labelValue returns v as a quoted Prometheus label value.
+func labelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return `"` + v + `"`
+}
+
+func orDefault(buckets, def []float64) []float64 {
+	if buckets == nil {
+		return def
+	}
+	return buckets
+}
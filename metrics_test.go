@@ -0,0 +1,99 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := &Metrics{DurationBuckets: []float64{60}, SizeBuckets: []float64{10, 1000}}
+	j := &JSONResponder{Observer: m}
+
+	mux := NewServeMux()
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			j.InternalServerError(w, errors.New("database down"))
+			return
+		}
+		j.UnprocessableEntity(w, "invalid email")
+	})
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		j.OK(w)
+	})
+	h := m.Handler(mux)
+
+	for _, target := range []string{"/users", "/users", "/users?fail=1"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, target, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/other", nil))
+
+	w := httptest.NewRecorder()
+	m.PrometheusHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("expected %#v, got %#v", "text/plain; version=0.0.4; charset=utf-8", ct)
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE jsonapi_responses_total counter",
+		`jsonapi_responses_total{route="POST /users",method="POST",status="422",class="4xx"} 2`,
+		`jsonapi_responses_total{route="POST /users",method="POST",status="500",class="5xx"} 1`,
+		`jsonapi_responses_total{route="/other",method="OTHER",status="200",class="2xx"} 1`,
+		"# TYPE jsonapi_response_duration_seconds histogram",
+		`jsonapi_response_duration_seconds_bucket{route="POST /users",method="POST",class="4xx",le="60"} 2`,
+		`jsonapi_response_duration_seconds_count{route="POST /users",method="POST",class="4xx"} 2`,
+		`jsonapi_response_size_bytes_bucket{route="POST /users",method="POST",class="4xx",le="10"} 0`,
+		`jsonapi_response_size_bytes_bucket{route="POST /users",method="POST",class="4xx",le="1000"} 2`,
+		`jsonapi_response_size_bytes_bucket{route="POST /users",method="POST",class="4xx",le="+Inf"} 2`,
+		`jsonapi_response_size_bytes_sum{route="POST /users",method="POST",class="4xx"} 72`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %#v in:\n%s", line, body)
+		}
+	}
+}
+
+// testMetricsPublished counts the runs of TestMetricsPublish, as expvar
+// names can only be published once.
+var testMetricsPublished atomic.Int64
+
+func TestMetricsPublish(t *testing.T) {
+	name := fmt.Sprintf("jsonapi_test_metrics_%d", testMetricsPublished.Add(1))
+	m := &Metrics{}
+	m.ObserveResponse(Observation{Status: http.StatusNotFound, Bytes: 42})
+	m.Publish(name)
+
+	var snapshot struct {
+		Responses []struct {
+			Status string
+			Count  int
+		}
+		SizeBytes []struct {
+			Sum   float64
+			Count int
+		} `json:"size_bytes"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &snapshot); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if len(snapshot.Responses) != 1 || snapshot.Responses[0].Status != "404" || snapshot.Responses[0].Count != 1 {
+		t.Errorf("expected one 404 response, got %#v", snapshot.Responses)
+	}
+	if len(snapshot.SizeBytes) != 1 || snapshot.SizeBytes[0].Sum != 42 {
+		t.Errorf("expected size sum %#v, got %#v", 42, snapshot.SizeBytes)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	if v := labelValue("a\"b\\c\nd"); v != `"a\"b\\c\nd"` {
+		t.Errorf("expected %#v, got %#v", `"a\"b\\c\nd"`, v)
+	}
+}
@@ -0,0 +1,120 @@
+package jsonapi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ServeMux is an http.ServeMux that answers requests it has no handler for
+// with JSON responses, instead of the plain-text pages of http.ServeMux.
+//
+// Requests for paths without a pattern are answered with NotFound. Requests
+// for paths with patterns for other methods only are answered with
+// MethodNotAllowed and an Allow header listing those methods, unless the
+// method is OPTIONS, in which case the Allow header is sent with status
+// code 204. Redirects to canonical paths are answered with JSON bodies too.
+//
+// Handlers must be registered with the Handle and HandleFunc methods of
+// ServeMux, not those of the embedded http.ServeMux. The zero value is ready
+// to use.
+type ServeMux struct {
+	http.ServeMux
+
+	// Responder writes the responses to requests without a handler. It
+	// defaults to DefaultResponder.
+	Responder *JSONResponder
+}
+
+// NewServeMux allocates and returns a new ServeMux.
+func NewServeMux() *ServeMux {
+	return new(ServeMux)
+}
+
+// route marks the handlers registered with a ServeMux, to tell them apart
+// from the handlers http.ServeMux answers unmatched requests with.
+type route struct {
+	http.Handler
+}
+
+// ServeHTTP calls the handler with a request-aware w bound to r, which
+// carries the matched pattern and path values.
+func (rt route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.Handler.ServeHTTP(rebind(w, r), r)
+}
+
+// Handle registers the handler for the given pattern, as described by
+// http.ServeMux.
+func (m *ServeMux) Handle(pattern string, handler http.Handler) {
+	m.ServeMux.Handle(pattern, route{handler})
+}
+
+// HandleFunc registers the handler function for the given pattern, as
+// described by http.ServeMux.
+func (m *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// ServeHTTP dispatches the request to the handler whose pattern most closely
+// matches the request URL, or answers it with a JSON response if there is
+// none.
+func (m *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, _ := m.Handler(r)
+	if _, ok := h.(route); ok || r.RequestURI == "*" {
+		m.ServeMux.ServeHTTP(w, r)
+		return
+	}
+
+	// h is one of the handlers http.ServeMux answers unmatched requests
+	// with. Run it against a probe to find out which.
+	probe := &probeWriter{header: make(http.Header), code: http.StatusOK}
+	h.ServeHTTP(probe, r)
+
+	j := responderOf(m.Responder)
+	switch probe.code {
+	case http.StatusMethodNotAllowed:
+		allow := probe.header.Get("Allow")
+		if !hasMethod(allow, http.MethodOptions) {
+			allow += ", " + http.MethodOptions
+		}
+		w.Header().Set("Allow", allow)
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		j.MethodNotAllowed(w)
+	case http.StatusMovedPermanently, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		w.Header().Set("Location", probe.header.Get("Location"))
+		j.Respond(w, probe.code)
+	default:
+		j.NotFound(w)
+	}
+}
+
+// probeWriter is an http.ResponseWriter that records the status code and
+// headers written to it, and discards the body.
+type probeWriter struct {
+	header http.Header
+	code   int
+}
+
+func (p *probeWriter) Header() http.Header {
+	return p.header
+}
+
+func (p *probeWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (p *probeWriter) WriteHeader(code int) {
+	p.code = code
+}
+
+// hasMethod reports whether the Allow header value allow lists method.
+func hasMethod(allow, method string) bool {
+	for _, m := range strings.Split(allow, ",") {
+		if strings.TrimSpace(m) == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testServeMux() *ServeMux {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		OK(w, r.PathValue("id"))
+	})
+	mux.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	})
+	mux.HandleFunc("/teams/", func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	})
+	return mux
+}
+
+func TestServeMuxDispatches(t *testing.T) {
+	w := httptest.NewRecorder()
+	testServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %#v, got %#v", http.StatusOK, w.Code)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "7" {
+		t.Errorf("expected %#v, got %#v", "7", resp.Data)
+	}
+}
+
+func TestServeMuxNotFound(t *testing.T) {
+	w := httptest.NewRecorder()
+	testServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/projects", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status code %#v, got %#v", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=UTF-8" {
+		t.Errorf("expected %#v, got %#v", "application/json; charset=UTF-8", ct)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("expected %#v, got %#v", http.StatusNotFound, resp.Code)
+	}
+}
+
+func TestServeMuxMethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	testServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/7", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %#v, got %#v", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("expected %#v, got %#v", "DELETE, GET, HEAD, OPTIONS", allow)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %#v, got %#v", http.StatusMethodNotAllowed, resp.Code)
+	}
+}
+
+func TestServeMuxOptions(t *testing.T) {
+	w := httptest.NewRecorder()
+	testServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users/7", nil))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status code %#v, got %#v", http.StatusNoContent, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("expected %#v, got %#v", "DELETE, GET, HEAD, OPTIONS", allow)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %#v", w.Body.String())
+	}
+}
+
+func TestServeMuxOptionsHandler(t *testing.T) {
+	mux := testServeMux()
+	mux.HandleFunc("OPTIONS /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Accepted(w)
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/users/7", nil))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status code %#v, got %#v", http.StatusAccepted, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/users/7", nil))
+	if allow := w.Header().Get("Allow"); allow != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("expected %#v, got %#v", "DELETE, GET, HEAD, OPTIONS", allow)
+	}
+}
+
+func TestServeMuxRedirect(t *testing.T) {
+	w := httptest.NewRecorder()
+	testServeMux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/teams", nil))
+
+	// Depending on the Go version, http.ServeMux redirects with 301 or 307.
+	if w.Code != http.StatusMovedPermanently && w.Code != http.StatusTemporaryRedirect {
+		t.Errorf("expected a redirect status code, got %#v", w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "/teams/" {
+		t.Errorf("expected %#v, got %#v", "/teams/", location)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+}
@@ -0,0 +1,130 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Kinds of failures of a response, reported in Observation.ErrorKind.
+const (
+	// ErrorKindDuplicate is a response refused by a Tracker because one was
+	// already sent.
+	ErrorKindDuplicate = "duplicate"
+
+	// ErrorKindClientClosed is a request-aware response that was not
+	// written, or not completely, because the client closed the request.
+	ErrorKindClientClosed = "client_closed"
+
+	// ErrorKindDeadline is a request-aware response that was not written,
+	// or not completely, because the request deadline was exceeded.
+	ErrorKindDeadline = "deadline_exceeded"
+
+	// ErrorKindEncode is a response whose data could not be encoded.
+	ErrorKindEncode = "encode"
+
+	// ErrorKindSign is a response whose content digest or signature could
+	// not be computed.
+	ErrorKindSign = "sign"
+
+	// ErrorKindWrite is a response that could not be written to the
+	// connection.
+	ErrorKindWrite = "write"
+)
+
+// Observation describes a response written by a JSONResponder.
+type Observation struct {
+	// Request is the request of a request-aware response, or nil.
+	Request *http.Request
+
+	// Pattern is the route pattern that matched the request, as in
+	// http.Request.Pattern, or "".
+	Pattern string
+
+	// Status is the status code of the response. Responses that were not
+	// written because the client closed the request have the status code
+	// StatusClientClosedRequest.
+	Status int
+
+	// Bytes is the number of bytes of the body that were written.
+	Bytes int64
+
+	// EncodeDuration is the time spent encoding and writing the body.
+	EncodeDuration time.Duration
+
+	// ContentType is the content type of the response, or "" if it has no
+	// body.
+	ContentType string
+
+	// DataType is the Go type of the data of the response, such as
+	// "*jsonapi.Error", or "" if no data was given.
+	DataType string
+
+	// Err is the data of the response if it is an error, or nil.
+	Err error
+
+	// ErrorCode is the application error code of the data of the response,
+	// or "". See ErrorCode.
+	ErrorCode string
+
+	// Written reports whether the response was written completely.
+	Written bool
+
+	// ErrorKind is why the response was not written completely, one of the
+	// ErrorKind constants, or "".
+	ErrorKind string
+}
+
+// Observer is notified of every response written by a JSONResponder. It is
+// the basis of metrics, audit logs and access logs.
+//
+// ObserveResponse is called synchronously once the response is written or
+// has failed, including when encoding panics, so it should be fast and must
+// not write to the response.
+type Observer interface {
+	ObserveResponse(o Observation)
+}
+
+// ObserverFunc is an adapter to use a function as an Observer.
+type ObserverFunc func(o Observation)
+
+// ObserveResponse calls f(o).
+func (f ObserverFunc) ObserveResponse(o Observation) {
+	f(o)
+}
+
+// Observers returns an Observer that notifies each of observers in turn.
+func Observers(observers ...Observer) Observer {
+	return ObserverFunc(func(o Observation) {
+		for _, observer := range observers {
+			observer.ObserveResponse(o)
+		}
+	})
+}
+
+// observe reports o to j.Observer, if set.
+func (j *JSONResponder) observe(o *Observation, data []interface{}) {
+	if j.Observer == nil {
+		return
+	}
+	if o.Request != nil {
+		o.Pattern = o.Request.Pattern
+	}
+	if len(data) > 0 {
+		o.DataType = fmt.Sprintf("%T", data[0])
+	}
+	j.Observer.ObserveResponse(*o)
+}
+
+// closed records in o that the response to r was not written because the
+// request context is done.
+func (o *Observation) closed(r *http.Request) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		o.ErrorKind = ErrorKindDeadline
+		return
+	}
+	o.Status = StatusClientClosedRequest
+	o.ErrorKind = ErrorKindClientClosed
+}
@@ -0,0 +1,194 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestObserver(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, test := range []struct {
+		name     string
+		respond  func(j *JSONResponder, r *http.Request)
+		expected Observation
+	}{
+		{
+			name: "ok",
+			respond: func(j *JSONResponder, r *http.Request) {
+				j.OK(httptest.NewRecorder(), "hello")
+			},
+			expected: Observation{
+				Status:      http.StatusOK,
+				Bytes:       int64(len(`{"code":200,"data":"hello"}` + "\n")),
+				ContentType: "application/json; charset=UTF-8",
+				DataType:    "string",
+				Written:     true,
+			},
+		},
+		{
+			name: "no content",
+			respond: func(j *JSONResponder, r *http.Request) {
+				j.NoContent(httptest.NewRecorder())
+			},
+			expected: Observation{Status: http.StatusNoContent, Written: true},
+		},
+		{
+			name: "error code",
+			respond: func(j *JSONResponder, r *http.Request) {
+				j.RespondError(httptest.NewRecorder(), errTestEmailTaken)
+			},
+			expected: Observation{
+				Status:      http.StatusConflict,
+				ContentType: "application/json; charset=UTF-8",
+				DataType:    "*jsonapi.Error",
+				ErrorCode:   "user.email_taken",
+				Written:     true,
+			},
+		},
+		{
+			name: "client closed",
+			respond: func(j *JSONResponder, r *http.Request) {
+				j.OK(WithRequest(httptest.NewRecorder(), r.WithContext(canceled)))
+			},
+			expected: Observation{Status: StatusClientClosedRequest, ErrorKind: ErrorKindClientClosed},
+		},
+		{
+			name: "write error",
+			respond: func(j *JSONResponder, r *http.Request) {
+				j.OK(WithRequest(failingWriter{httptest.NewRecorder()}, r))
+			},
+			expected: Observation{
+				Status:      http.StatusOK,
+				ContentType: "application/json; charset=UTF-8",
+				ErrorKind:   ErrorKindWrite,
+			},
+		},
+		{
+			name: "duplicate",
+			respond: func(j *JSONResponder, r *http.Request) {
+				w := (&Tracker{OnDuplicate: func(DuplicateResponse) {}}).Wrap(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusOK)
+				j.NotFound(w)
+			},
+			expected: Observation{Status: http.StatusNotFound, ErrorKind: ErrorKindDuplicate},
+		},
+		{
+			name: "encode",
+			respond: func(j *JSONResponder, r *http.Request) {
+				defer func() { recover() }()
+				j.OK(httptest.NewRecorder(), make(chan int))
+			},
+			expected: Observation{
+				Status:    http.StatusOK,
+				DataType:  "chan int",
+				ErrorKind: ErrorKindEncode,
+			},
+		},
+	} {
+		var observed []Observation
+		j := &JSONResponder{Observer: ObserverFunc(func(o Observation) {
+			observed = append(observed, o)
+		})}
+
+		test.respond(j, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if len(observed) != 1 {
+			t.Errorf("%s: expected %#v observations, got %#v", test.name, 1, len(observed))
+			continue
+		}
+		o := observed[0]
+		if o.Written {
+			if o.Bytes == 0 && o.ContentType != "" {
+				t.Errorf("%s: expected bytes to be counted", test.name)
+			}
+			if test.expected.Bytes == 0 {
+				test.expected.Bytes = o.Bytes
+			}
+		}
+		if o.ErrorCode != "" && !errors.Is(o.Err, errTestEmailTaken) {
+			t.Errorf("%s: expected error with code %#v, got %#v", test.name, o.ErrorCode, o.Err)
+		}
+		o.Request, o.EncodeDuration, o.Err = nil, 0, nil
+		if o != test.expected {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.expected, o)
+		}
+	}
+}
+
+func TestObserverPattern(t *testing.T) {
+	var observed Observation
+	j := &JSONResponder{Observer: ObserverFunc(func(o Observation) { observed = o })}
+
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		j.OK(w, r.PathValue("id"))
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	RequestAware(mux).ServeHTTP(httptest.NewRecorder(), r)
+
+	if observed.Pattern != "GET /users/{id}" {
+		t.Errorf("expected %#v, got %#v", "GET /users/{id}", observed.Pattern)
+	}
+	if observed.Request == nil || observed.Request.PathValue("id") != "42" {
+		t.Errorf("expected request of the route, got %#v", observed.Request)
+	}
+}
+
+func TestObservers(t *testing.T) {
+	var calls []string
+	o := Observers(
+		ObserverFunc(func(Observation) { calls = append(calls, "first") }),
+		ObserverFunc(func(Observation) { calls = append(calls, "second") }),
+	)
+	o.ObserveResponse(Observation{})
+
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("expected %#v, got %#v", []string{"first", "second"}, calls)
+	}
+}
+
+func TestObserverMiddleware(t *testing.T) {
+	var observed []int
+	j := &JSONResponder{Observer: ObserverFunc(func(o Observation) {
+		observed = append(observed, o.Status)
+	})}
+
+	mux := &ServeMux{Responder: j}
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {})
+
+	limiter := &RateLimiter{Limit: 1, Period: time.Hour, Responder: j}
+	limiter.Allow(KeyByIP(httptest.NewRequest(http.MethodGet, "/", nil)))
+
+	for _, test := range []struct {
+		name     string
+		handler  http.Handler
+		expected int
+	}{
+		{"rate limiter", limiter.Handler(http.NotFoundHandler()), http.StatusTooManyRequests},
+		{"authenticator", (&Authenticator{Bearer: func(*http.Request, string) (interface{}, error) {
+			return nil, nil
+		}, Responder: j}).Handler(http.NotFoundHandler()), http.StatusUnauthorized},
+		{"serve mux", mux, http.StatusMethodNotAllowed},
+		{"interceptor", (&Interceptor{Responder: j}).Handler(http.NotFoundHandler()), http.StatusNotFound},
+		{"redirector", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			(&Redirector{Responder: j}).Found(w, r, "/login")
+		}), http.StatusFound},
+		{"time limiter", (&TimeLimiter{Timeout: time.Millisecond, Responder: j}).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})), http.StatusServiceUnavailable},
+	} {
+		observed = nil
+		test.handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+		if len(observed) != 1 || observed[0] != test.expected {
+			t.Errorf("%s: expected %#v, got %#v", test.name, []int{test.expected}, observed)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package jsonapi
+
+import "net/http"
+
+// Problem is a problem details object, as defined by RFC 9457. It is an
+// alternative to the default JSON structure for error responses, for clients
+// that understand the application/problem+json media type.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Code is the application error code of the problem, written as an
+	// extension member. See ErrorCode.
+	Code string `json:"code,omitempty"`
+}
+
+// RespondProblem writes p as application/problem+json with the status code
+// p.Status, using DefaultResponder. If p.Title is empty, the status text is
+// written as the title.
+func RespondProblem(w http.ResponseWriter, p *Problem) {
+	DefaultResponder.RespondProblem(w, p)
+}
+
+// RespondProblem writes p as application/problem+json with the status code
+// p.Status. If p.Title is empty, the status text is written as the title; p
+// itself is not modified.
+func (j *JSONResponder) RespondProblem(w http.ResponseWriter, p *Problem) {
+	problem := *p
+	if problem.Title == "" {
+		problem.Title = StatusText(problem.Status)
+	}
+	j.write(w, problem.Status, nil, true, []interface{}{&problem})
+}
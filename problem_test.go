@@ -0,0 +1,72 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRespondProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	RespondProblem(w, &Problem{
+		Type:   "https://example.com/probs/out-of-credit",
+		Status: http.StatusForbidden,
+		Detail: "Your current balance is 30, but that costs 50.",
+	})
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status code %#v, got %#v", http.StatusForbidden, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json; charset=UTF-8" {
+		t.Errorf("expected %#v, got %#v", "application/problem+json; charset=UTF-8", ct)
+	}
+
+	p := &Problem{}
+	if err := json.NewDecoder(w.Body).Decode(p); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if p.Title != http.StatusText(http.StatusForbidden) {
+		t.Errorf("expected %#v, got %#v", http.StatusText(http.StatusForbidden), p.Title)
+	}
+	if p.Type != "https://example.com/probs/out-of-credit" {
+		t.Errorf("expected %#v, got %#v", "https://example.com/probs/out-of-credit", p.Type)
+	}
+}
+
+func TestRespondProblemDoesNotModifyProblem(t *testing.T) {
+	p := &Problem{Status: http.StatusNotFound}
+	RespondProblem(httptest.NewRecorder(), p)
+
+	if p.Title != "" {
+		t.Errorf("expected %#v, got %#v", "", p.Title)
+	}
+}
+
+func TestRespondProblemPipeline(t *testing.T) {
+	var observed []Observation
+	j := &JSONResponder{
+		Observer:      ObserverFunc(func(o Observation) { observed = append(observed, o) }),
+		ContentDigest: []string{DigestSHA256},
+	}
+	tracker := &Tracker{OnDuplicate: func(DuplicateResponse) {}}
+
+	h := tracker.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		j.RespondProblem(w, &Problem{Status: http.StatusConflict, Detail: "taken"})
+		j.RespondProblem(w, &Problem{Status: http.StatusConflict, Detail: "twice"})
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	expected := `{"title":"Conflict","status":409,"detail":"taken"}` + "\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected %#v, got %#v", expected, w.Body.String())
+	}
+	if digest, _ := ContentDigest([]byte(expected)); w.Header().Get("Content-Digest") != digest {
+		t.Errorf("expected %#v, got %#v", digest, w.Header().Get("Content-Digest"))
+	}
+	if len(observed) != 2 || !observed[0].Written || observed[1].ErrorKind != ErrorKindDuplicate {
+		t.Errorf("expected a written and a duplicate observation, got %#v", observed)
+	}
+}
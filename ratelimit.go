@@ -0,0 +1,204 @@
+package jsonapi
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeyFunc extracts the key that a request is rate limited by. Requests with
+// an empty key are not rate limited.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP limits requests by the client IP address in r.RemoteAddr.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader limits requests by the value of the given header, such as an
+// API key.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// KeyByRoute limits requests by method and route pattern, so that each route
+// has its own limit shared by all clients and paths, such as /users/1 and
+// /users/2 for the pattern "/users/{id}". The pattern is set by
+// http.ServeMux, so the limiter must wrap the handlers it routes to. Requests
+// without a pattern are limited by method and path.
+func KeyByRoute(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Method + " " + r.Pattern
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+// RateLimiter is a middleware that limits the rate of requests per key using
+// the generic cell rate algorithm, which behaves like a token bucket that
+// refills continuously.
+//
+// Rejected requests are answered with TooManyRequestsRetry. All answered
+// requests carry the RateLimit-Policy and RateLimit headers.
+type RateLimiter struct {
+	// Limit is the number of requests allowed per Period.
+	Limit int
+
+	// Period is the time window Limit applies to.
+	Period time.Duration
+
+	// Burst is the number of requests that may be made at once. It defaults
+	// to Limit.
+	Burst int
+
+	// Key extracts the key requests are limited by. It defaults to KeyByIP.
+	Key KeyFunc
+
+	// Policy names the quota policy in the RateLimit headers.
+	Policy string
+
+	// Responder writes the responses to rejected requests. It defaults to
+	// DefaultResponder.
+	Responder *JSONResponder
+
+	once   sync.Once
+	shards [rateLimitShards]rateLimitShard
+}
+
+const rateLimitShards = 32
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	tats    map[string]time.Time
+	swept   time.Time
+	pending int
+}
+
+// Handler returns a handler that rate limits requests to next.
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.key(r)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retry, ok := l.Allow(key)
+		if !ok {
+			responderOf(l.Responder).TooManyRequestsRetry(w, retry)
+			return
+		}
+
+		retry.setHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Allow records a request for key and reports whether it is allowed. The
+// returned Retry describes the state of the limit for key, and when to retry
+// if the request was rejected.
+func (l *RateLimiter) Allow(key string) (Retry, bool) {
+	l.once.Do(l.init)
+
+	now := timeNow()
+	interval := l.interval()
+	burst := time.Duration(l.burst()) * interval
+
+	shard := &l.shards[shardIndex(key)]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.sweep(now)
+
+	// tat is the theoretical arrival time: the time at which the bucket for
+	// key is full again.
+	tat, ok := shard.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(interval)
+	allowAt := next.Add(-burst)
+	allowed := !now.Before(allowAt)
+	if allowed {
+		shard.tats[key] = next
+		tat = next
+	}
+
+	rl := &RateLimit{
+		Policy:    l.Policy,
+		Quota:     l.Limit,
+		Window:    l.Period,
+		Remaining: int((burst - tat.Sub(now)) / interval),
+		Reset:     tat.Sub(now),
+	}
+	if rl.Remaining < 0 {
+		rl.Remaining = 0
+	}
+
+	retry := Retry{RateLimit: rl}
+	if !allowed {
+		retry.After = allowAt.Sub(now)
+	}
+	return retry, allowed
+}
+
+func (l *RateLimiter) init() {
+	if l.Limit <= 0 || l.Period <= 0 {
+		panic("jsonapi: RateLimiter requires a positive Limit and Period")
+	}
+	if l.interval() <= 0 {
+		panic("jsonapi: RateLimiter Period divided by Limit must be at least 1ns")
+	}
+	for i := range l.shards {
+		l.shards[i].tats = make(map[string]time.Time)
+	}
+}
+
+func (l *RateLimiter) key(r *http.Request) string {
+	if l.Key == nil {
+		return KeyByIP(r)
+	}
+	return l.Key(r)
+}
+
+func (l *RateLimiter) interval() time.Duration {
+	return l.Period / time.Duration(l.Limit)
+}
+
+func (l *RateLimiter) burst() int {
+	if l.Burst <= 0 {
+		return l.Limit
+	}
+	return l.Burst
+}
+
+// sweep removes keys whose bucket is full again, as they carry no state. It
+// runs at most once per second, and only after enough requests since the
+// last sweep to be worth it.
+func (s *rateLimitShard) sweep(now time.Time) {
+	s.pending++
+	if s.pending < 64 || now.Sub(s.swept) < time.Second {
+		return
+	}
+	for key, tat := range s.tats {
+		if !tat.After(now) {
+			delete(s.tats, key)
+		}
+	}
+	s.swept = now
+	s.pending = 0
+}
+
+func shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % rateLimitShards)
+}
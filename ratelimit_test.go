@@ -0,0 +1,165 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRejectsOverLimit(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	l := &RateLimiter{Limit: 2, Period: time.Minute}
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	}))
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != want {
+			t.Errorf("request %d: expected status code %#v, got %#v", i, want, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if h := w.Header().Get("Retry-After"); h != "30" {
+		t.Errorf("expected %#v, got %#v", "30", h)
+	}
+	if h := w.Header().Get("RateLimit"); h != `"default";r=0;t=60` {
+		t.Errorf("expected %#v, got %#v", `"default";r=0;t=60`, h)
+	}
+
+	// Half the period restores one request.
+	now = now.Add(30 * time.Second)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %#v, got %#v", http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimiterRemaining(t *testing.T) {
+	l := &RateLimiter{Limit: 10, Period: time.Second, Policy: "api"}
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if h := w.Header().Get("RateLimit-Policy"); h != `"api";q=10;w=1` {
+		t.Errorf("expected %#v, got %#v", `"api";q=10;w=1`, h)
+	}
+	if h := w.Header().Get("RateLimit"); h != `"api";r=9;t=1` {
+		t.Errorf("expected %#v, got %#v", `"api";r=9;t=1`, h)
+	}
+	if h := w.Header().Get("Retry-After"); h != "" {
+		t.Errorf("expected %#v, got %#v", "", h)
+	}
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	l := &RateLimiter{Limit: 1, Period: time.Hour, Key: KeyByHeader("X-API-Key")}
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	}))
+
+	for _, test := range []struct {
+		key  string
+		code int
+	}{
+		{key: "a", code: http.StatusOK},
+		{key: "b", code: http.StatusOK},
+		{key: "a", code: http.StatusTooManyRequests},
+		{key: "", code: http.StatusOK},
+		{key: "", code: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-API-Key", test.key)
+		h.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("key %#v: expected status code %#v, got %#v", test.key, test.code, w.Code)
+		}
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := &RateLimiter{Limit: 60, Period: time.Minute, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Allow("k"); !ok {
+			t.Errorf("request %d: expected to be allowed", i)
+		}
+	}
+	if _, ok := l.Allow("k"); ok {
+		t.Errorf("expected request over burst to be rejected")
+	}
+}
+
+func TestRateLimiterSweepsExpiredKeys(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	l := &RateLimiter{Limit: 1, Period: time.Second}
+	shard := &l.shards[shardIndex("k")]
+
+	l.Allow("k")
+	now = now.Add(time.Hour)
+	for i := 0; i < 64; i++ {
+		shard.sweep(now)
+	}
+
+	if n := len(shard.tats); n != 0 {
+		t.Errorf("expected expired keys to be removed, got %#v keys", n)
+	}
+}
+
+func TestKeyByIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.7:5123"
+	if key := KeyByIP(r); key != "203.0.113.7" {
+		t.Errorf("expected %#v, got %#v", "203.0.113.7", key)
+	}
+}
+
+func TestKeyByRoute(t *testing.T) {
+	l := &RateLimiter{Limit: 1, Period: time.Hour, Key: KeyByRoute}
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		OK(w)
+	})))
+
+	for _, test := range []struct {
+		target string
+		code   int
+	}{
+		{target: "/users/1", code: http.StatusOK},
+		{target: "/users/2", code: http.StatusTooManyRequests},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.target, nil))
+		if w.Code != test.code {
+			t.Errorf("%s: expected status code %#v, got %#v", test.target, test.code, w.Code)
+		}
+	}
+
+	if key := KeyByRoute(httptest.NewRequest(http.MethodGet, "/users/1", nil)); key != "GET /users/1" {
+		t.Errorf("expected %#v, got %#v", "GET /users/1", key)
+	}
+}
+
+func TestRateLimiterInvalidInterval(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("expected a panic, got %#v", err)
+		}
+	}()
+	(&RateLimiter{Limit: 2, Period: time.Nanosecond}).Allow("k")
+}
@@ -0,0 +1,52 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// ErrInvalidRaw is the error of responses whose raw JSON data is not valid
+// JSON, when JSONResponder.ValidateRaw is set.
+var ErrInvalidRaw = errors.New("jsonapi: invalid raw JSON data")
+
+// Raw is data that is already encoded as JSON. Used as the data of a
+// response, it is written verbatim, like json.RawMessage, without being
+// parsed or re-encoded. It is meant for pre-serialized payloads, such as
+// cached responses.
+type Raw []byte
+
+// MarshalJSON returns r, or null if r is empty.
+func (r Raw) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// rawPayload returns data as JSON if it is already encoded or encodes
+// itself.
+func rawPayload(data interface{}) ([]byte, bool, error) {
+	var payload []byte
+	switch d := data.(type) {
+	case Raw:
+		payload = d
+	case json.RawMessage:
+		payload = d
+	case json.Marshaler:
+		if v := reflect.ValueOf(d); v.Kind() == reflect.Ptr && v.IsNil() {
+			return []byte("null"), true, nil
+		}
+		b, err := d.MarshalJSON()
+		if err != nil {
+			return nil, true, &json.MarshalerError{Type: reflect.TypeOf(d), Err: err}
+		}
+		payload = b
+	default:
+		return nil, false, nil
+	}
+	if len(payload) == 0 {
+		payload = []byte("null")
+	}
+	return payload, true, nil
+}
@@ -0,0 +1,85 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type nilMarshaler struct{}
+
+func (*nilMarshaler) MarshalJSON() ([]byte, error) {
+	panic("MarshalJSON called on nil pointer")
+}
+
+func TestRespondRaw(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, test := range []struct {
+		data     interface{}
+		expected string
+	}{
+		{Raw(`{"foo": "bar"}`), `{"code":200,"data":{"foo": "bar"}}` + "\n"},
+		{json.RawMessage(`[1,2,3]`), `{"code":200,"data":[1,2,3]}` + "\n"},
+		{Raw(`"<b>"`), `{"code":200,"data":"<b>"}` + "\n"},
+		{Raw(nil), `{"code":200,"data":null}` + "\n"},
+		{at, `{"code":200,"data":"2026-01-02T03:04:05Z"}` + "\n"},
+		{(*nilMarshaler)(nil), `{"code":200,"data":null}` + "\n"},
+		{Raw(`{not json`), `{"code":200,"data":{not json}` + "\n"},
+	} {
+		w := httptest.NewRecorder()
+		OK(w, test.data)
+
+		if body := w.Body.String(); body != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, body)
+		}
+	}
+}
+
+func TestRespondRawMeta(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondMeta(w, http.StatusOK, map[string]interface{}{"page": 2}, Raw(`[]`))
+
+	expected := `{"code":200,"data":[],"meta":{"page":2}}` + "\n"
+	if body := w.Body.String(); body != expected {
+		t.Errorf("expected %#v, got %#v", expected, body)
+	}
+}
+
+func TestRespondRawMatchesEncoding(t *testing.T) {
+	data := map[string]interface{}{"id": 1, "name": "Ada"}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("expected %#v, got %#v", nil, err)
+	}
+
+	encoded := httptest.NewRecorder()
+	respondMeta(encoded, http.StatusCreated, map[string]interface{}{"location": "/users/1"}, data)
+
+	verbatim := httptest.NewRecorder()
+	respondMeta(verbatim, http.StatusCreated, map[string]interface{}{"location": "/users/1"}, Raw(raw))
+
+	if !bytes.Equal(encoded.Body.Bytes(), verbatim.Body.Bytes()) {
+		t.Errorf("expected %#v, got %#v", encoded.Body.String(), verbatim.Body.String())
+	}
+}
+
+func TestValidateRaw(t *testing.T) {
+	j := &JSONResponder{ValidateRaw: true}
+
+	w := httptest.NewRecorder()
+	j.OK(w, Raw(`{"valid":true}`))
+	if body := w.Body.String(); body != `{"code":200,"data":{"valid":true}}`+"\n" {
+		t.Errorf("expected valid raw data to be written, got %#v", body)
+	}
+
+	defer func() {
+		if err := recover(); err != ErrInvalidRaw {
+			t.Errorf("expected %#v, got %#v", ErrInvalidRaw, err)
+		}
+	}()
+	j.OK(httptest.NewRecorder(), Raw(`{not json`))
+}
@@ -0,0 +1,185 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsafeRedirect is returned when a redirect target points to a host that
+// is not allowed, or cannot be safely used as a Location header.
+var ErrUnsafeRedirect = errors.New("jsonapi: unsafe redirect target")
+
+// Redirector writes redirect responses that set the Location header.
+//
+// Relative targets are resolved against the request URL. Targets that name a
+// host are only allowed if the host is the request host or is listed in
+// AllowedHosts, which guards against open redirects. Rejected targets are
+// reported with ErrUnsafeRedirect and nothing is written.
+type Redirector struct {
+	// AllowedHosts lists the hosts, other than the request host, that
+	// redirects may point to. An entry of the form "*.example.com" allows any
+	// subdomain of example.com. Entries without a port match any port.
+	AllowedHosts []string
+
+	// IncludeLocation adds the resolved target to the response body as
+	// meta.location, for API clients that do not follow redirects.
+	IncludeLocation bool
+
+	// Responder writes the redirect responses. It defaults to
+	// DefaultResponder.
+	Responder *JSONResponder
+}
+
+// DefaultRedirector is the Redirector used by the package-level redirect
+// functions. It only allows redirects to the request host.
+var DefaultRedirector = &Redirector{}
+
+// Redirect writes data with the given redirect status code and sets the
+// Location header to target.
+//
+// Redirect panics if code is not a 3xx status code.
+func (rd *Redirector) Redirect(w http.ResponseWriter, r *http.Request, code int, target string, data ...interface{}) error {
+	if code < 300 || code > 399 {
+		panic(fmt.Sprintf("jsonapi: invalid redirect code %d", code))
+	}
+
+	location, err := rd.resolve(r, target)
+	if err != nil {
+		return err
+	}
+
+	var meta map[string]interface{}
+	if rd.IncludeLocation {
+		meta = map[string]interface{}{"location": location}
+	}
+
+	w.Header().Set("Location", location)
+	responderOf(rd.Responder).respond(w, code, meta, data...)
+	return nil
+}
+
+// MovedPermanently redirects to target with status code 301.
+func (rd *Redirector) MovedPermanently(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return rd.Redirect(w, r, http.StatusMovedPermanently, target, data...)
+}
+
+// Found redirects to target with status code 302.
+func (rd *Redirector) Found(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return rd.Redirect(w, r, http.StatusFound, target, data...)
+}
+
+// SeeOther redirects to target with status code 303.
+func (rd *Redirector) SeeOther(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return rd.Redirect(w, r, http.StatusSeeOther, target, data...)
+}
+
+// TemporaryRedirect redirects to target with status code 307.
+func (rd *Redirector) TemporaryRedirect(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return rd.Redirect(w, r, http.StatusTemporaryRedirect, target, data...)
+}
+
+// PermanentRedirect redirects to target with status code 308.
+func (rd *Redirector) PermanentRedirect(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return rd.Redirect(w, r, http.StatusPermanentRedirect, target, data...)
+}
+
+// resolve returns the Location header value for target, or an error wrapping
+// ErrUnsafeRedirect if target may not be redirected to.
+func (rd *Redirector) resolve(r *http.Request, target string) (string, error) {
+	// Browsers treat backslashes as slashes, so "/\evil.com" would be
+	// followed as "//evil.com". Control characters are never valid.
+	if strings.ContainsAny(target, "\\") || strings.IndexFunc(target, isControl) >= 0 {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeRedirect, target)
+	}
+
+	ref, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsafeRedirect, err)
+	}
+	// A scheme without a host, as in "https:/evil.com", is resolved by
+	// browsers against the scheme of the page, which may make the path a
+	// host.
+	if ref.Opaque != "" || (ref.Scheme != "" && ref.Scheme != "http" && ref.Scheme != "https") || (ref.Scheme != "" && ref.Host == "") {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeRedirect, target)
+	}
+	// Browsers follow paths with several leading slashes, as in
+	// "///evil.com", as scheme-relative URLs.
+	if ref.Host == "" && strings.HasPrefix(ref.Path, "//") {
+		return "", fmt.Errorf("%w: %q", ErrUnsafeRedirect, target)
+	}
+	if ref.Host != "" && !rd.allowed(r, ref.Host) {
+		return "", fmt.Errorf("%w: host %q is not allowed", ErrUnsafeRedirect, ref.Host)
+	}
+
+	base := &url.URL{Path: "/"}
+	if r != nil && r.URL != nil {
+		base = &url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath}
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// allowed reports whether host may be redirected to.
+func (rd *Redirector) allowed(r *http.Request, host string) bool {
+	host = strings.ToLower(host)
+	if r != nil && strings.ToLower(r.Host) == host {
+		return true
+	}
+
+	hostname := host
+	if u := (&url.URL{Host: host}); u.Port() != "" {
+		hostname = u.Hostname()
+	}
+
+	for _, allowed := range rd.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix := strings.TrimPrefix(allowed, "*"); suffix != allowed {
+			if strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix) {
+				return true
+			}
+			continue
+		}
+		if allowed == host || allowed == hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// isControl reports whether r is an ASCII control character.
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// Redirect writes data with the given redirect status code and sets the
+// Location header to target, using DefaultRedirector.
+func Redirect(w http.ResponseWriter, r *http.Request, code int, target string, data ...interface{}) error {
+	return DefaultRedirector.Redirect(w, r, code, target, data...)
+}
+
+// MovedPermanentlyTo redirects to target with status code 301.
+func MovedPermanentlyTo(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return DefaultRedirector.MovedPermanently(w, r, target, data...)
+}
+
+// FoundTo redirects to target with status code 302.
+func FoundTo(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return DefaultRedirector.Found(w, r, target, data...)
+}
+
+// SeeOtherTo redirects to target with status code 303.
+func SeeOtherTo(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return DefaultRedirector.SeeOther(w, r, target, data...)
+}
+
+// TemporaryRedirectTo redirects to target with status code 307.
+func TemporaryRedirectTo(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return DefaultRedirector.TemporaryRedirect(w, r, target, data...)
+}
+
+// PermanentRedirectTo redirects to target with status code 308.
+func PermanentRedirectTo(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error {
+	return DefaultRedirector.PermanentRedirect(w, r, target, data...)
+}
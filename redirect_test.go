@@ -0,0 +1,143 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectSetsLocation(t *testing.T) {
+	for _, test := range []struct {
+		f    func(w http.ResponseWriter, r *http.Request, target string, data ...interface{}) error
+		code int
+	}{
+		{f: MovedPermanentlyTo, code: http.StatusMovedPermanently},
+		{f: FoundTo, code: http.StatusFound},
+		{f: SeeOtherTo, code: http.StatusSeeOther},
+		{f: TemporaryRedirectTo, code: http.StatusTemporaryRedirect},
+		{f: PermanentRedirectTo, code: http.StatusPermanentRedirect},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/users/1", nil)
+
+		if err := test.f(w, r, "/users/2"); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if w.Code != test.code {
+			t.Errorf("expected status code %#v, got %#v", test.code, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != "/users/2" {
+			t.Errorf("expected %#v, got %#v", "/users/2", location)
+		}
+	}
+}
+
+func TestRedirectResolvesRelativeTarget(t *testing.T) {
+	for _, test := range []struct {
+		target   string
+		location string
+	}{
+		{target: "edit", location: "/users/edit"},
+		{target: "../teams?page=2", location: "/teams?page=2"},
+		{target: "?tab=profile", location: "/users/1?tab=profile"},
+		{target: "http://example.com/login", location: "http://example.com/login"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/users/1", nil)
+
+		if err := FoundTo(w, r, test.target); err != nil {
+			t.Errorf("expected %#v, got %#v", nil, err)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("expected %#v, got %#v", test.location, location)
+		}
+	}
+}
+
+func TestRedirectRejectsUnsafeTarget(t *testing.T) {
+	for _, target := range []string{
+		"https://evil.com/",
+		"//evil.com/",
+		"/\\evil.com",
+		"javascript:alert(1)",
+		"mailto:someone@example.com",
+		"/foo\r\nSet-Cookie: x=y",
+		"https:/evil.com",
+		"http:/evil.com/x",
+		"///evil.com",
+		"////evil.com",
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+
+		err := FoundTo(w, r, target)
+		if !errors.Is(err, ErrUnsafeRedirect) {
+			t.Errorf("expected ErrUnsafeRedirect for %#v, got %#v", target, err)
+		}
+		if w.Header().Get("Location") != "" || w.Body.Len() != 0 {
+			t.Errorf("expected nothing to be written for %#v", target)
+		}
+	}
+}
+
+func TestRedirectAllowedHosts(t *testing.T) {
+	rd := &Redirector{AllowedHosts: []string{"accounts.example.org", "*.cdn.example.net"}}
+
+	for _, test := range []struct {
+		target  string
+		allowed bool
+	}{
+		{target: "https://accounts.example.org/login", allowed: true},
+		{target: "https://accounts.example.org:8443/login", allowed: true},
+		{target: "https://eu.cdn.example.net/a.png", allowed: true},
+		{target: "https://cdn.example.net/a.png", allowed: false},
+		{target: "https://evilcdn.example.net/a.png", allowed: false},
+		{target: "https://example.org/", allowed: false},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+
+		err := rd.Found(w, r, test.target)
+		if test.allowed && err != nil {
+			t.Errorf("expected %#v to be allowed, got %#v", test.target, err)
+		}
+		if !test.allowed && !errors.Is(err, ErrUnsafeRedirect) {
+			t.Errorf("expected %#v to be rejected, got %#v", test.target, err)
+		}
+	}
+}
+
+func TestRedirectIncludeLocation(t *testing.T) {
+	rd := &Redirector{IncludeLocation: true}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/users", nil)
+
+	if err := rd.SeeOther(w, r, "/users/1"); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != http.StatusText(http.StatusSeeOther) {
+		t.Errorf("expected %#v, got %#v", http.StatusText(http.StatusSeeOther), resp.Data)
+	}
+	if resp.Meta["location"] != "/users/1" {
+		t.Errorf("expected %#v, got %#v", "/users/1", resp.Meta["location"])
+	}
+}
+
+func TestRedirectInvalidCodePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Redirect to panic for a non-3xx code")
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	Redirect(w, r, http.StatusOK, "/")
+}
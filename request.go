@@ -0,0 +1,116 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the nonstandard status code 499, used by nginx
+// for requests that the client closed before the response was written.
+const StatusClientClosedRequest = 499
+
+// WithRequest returns an http.ResponseWriter that makes every response written
+// through it aware of r.
+//
+// Request-aware responses are not written once the context of r is done,
+// which saves encoding large payloads for clients that have gone away, and
+// writing is aborted if the context ends while the response is encoded.
+// Requests whose client closed the connection are reported to the
+// OnClientClosed hook of the JSONResponder.
+func WithRequest(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	return &requestWriter{ResponseWriter: w, req: r}
+}
+
+// RequestAware returns a handler that makes every response written by next
+// aware of its request. See WithRequest.
+func RequestAware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(WithRequest(w, r), r)
+	})
+}
+
+// requestWriter is an http.ResponseWriter bound to its request.
+type requestWriter struct {
+	http.ResponseWriter
+	req *http.Request
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for
+// http.ResponseController.
+func (rw *requestWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// requestOf returns the request w was bound to by WithRequest, or nil if it
+// is not request-aware.
+func requestOf(w http.ResponseWriter) *http.Request {
+	var req *http.Request
+	unwrap(w, func(w http.ResponseWriter) bool {
+		if rw, ok := w.(*requestWriter); ok {
+			req = rw.req
+		}
+		return req != nil
+	})
+	return req
+}
+
+// unwrap calls f with w and each writer it wraps, from the outermost in,
+// until f returns true. Writers that wrap another writer must implement
+// Unwrap to be seen through, like for http.ResponseController.
+func unwrap(w http.ResponseWriter, f func(w http.ResponseWriter) bool) {
+	for w != nil && !f(w) {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = u.Unwrap()
+	}
+}
+
+// rebind returns w bound to r if w is request-aware, or w otherwise. It is
+// used by middleware that replaces the request, so that responses see the
+// new request.
+func rebind(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if requestOf(w) == nil {
+		return w
+	}
+	return WithRequest(w, r)
+}
+
+// clientClosed reports that the response to r was not written. Responses
+// abandoned because a deadline was exceeded, such as those of handlers run
+// by Timeout, are not reported, as the client did not close the request.
+func (j *JSONResponder) clientClosed(r *http.Request, status int) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		return
+	}
+	if j.OnClientClosed != nil {
+		j.OnClientClosed(r, status)
+	}
+}
+
+// contextWriter is an io.Writer that fails once ctx is done, if ctx is not
+// nil. It records the first write error and the number of bytes written.
+type contextWriter struct {
+	w   io.Writer
+	ctx context.Context
+	err error
+	n   int64
+}
+
+func (cw *contextWriter) Write(b []byte) (int, error) {
+	if cw.ctx != nil {
+		if err := cw.ctx.Err(); err != nil {
+			cw.err = err
+			return 0, err
+		}
+	}
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	if err != nil {
+		cw.err = err
+	}
+	return n, err
+}
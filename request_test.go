@@ -0,0 +1,106 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failingWriter is an http.ResponseWriter whose writes fail, like those of a
+// connection the client has closed.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (fw failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("write: broken pipe")
+}
+
+func TestRespondSkipsClosedRequest(t *testing.T) {
+	var closed []int
+	j := &JSONResponder{OnClientClosed: func(r *http.Request, status int) {
+		closed = append(closed, status)
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	j.OK(WithRequest(w, r), map[string]string{"foo": "bar"})
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %#v", w.Body.String())
+	}
+	if len(closed) != 1 || closed[0] != http.StatusOK {
+		t.Errorf("expected %#v, got %#v", []int{http.StatusOK}, closed)
+	}
+}
+
+func TestRespondAbortsOnWriteError(t *testing.T) {
+	var closed []int
+	j := &JSONResponder{OnClientClosed: func(r *http.Request, status int) {
+		closed = append(closed, status)
+	}}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := failingWriter{httptest.NewRecorder()}
+
+	j.NotFound(WithRequest(w, r))
+
+	if len(closed) != 1 || closed[0] != http.StatusNotFound {
+		t.Errorf("expected %#v, got %#v", []int{http.StatusNotFound}, closed)
+	}
+}
+
+func TestRespondIgnoresExceededDeadline(t *testing.T) {
+	called := false
+	j := &JSONResponder{OnClientClosed: func(r *http.Request, status int) {
+		called = true
+	}}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	j.OK(WithRequest(w, r))
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %#v", w.Body.String())
+	}
+	if called {
+		t.Errorf("expected OnClientClosed not to be called for an exceeded deadline")
+	}
+}
+
+func TestRespondRequestAwarePanicsOnEncodeError(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected encoding errors to panic")
+		}
+	}()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	OK(WithRequest(httptest.NewRecorder(), r), make(chan int))
+}
+
+func TestRequestAware(t *testing.T) {
+	var got *http.Request
+	h := RequestAware(Intercept(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = requestOf(w)
+	})))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != r {
+		t.Errorf("expected %#v, got %#v", r, got)
+	}
+	if requestOf(httptest.NewRecorder()) != nil {
+		t.Errorf("expected a plain writer not to be request-aware")
+	}
+}
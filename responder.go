@@ -0,0 +1,573 @@
+package jsonapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statusSwitchProxy is the unused status code 306, which has no constant in
+// net/http.
+const statusSwitchProxy = 306
+
+// JSONResponder is the Responder behind the package-level functions. The zero
+// value writes responses the same way the package-level functions do, and
+// its fields customize how responses are written.
+type JSONResponder struct {
+	// OnClientClosed, if set, is called when a request-aware response is not
+	// written because the client closed the request. status is the status
+	// code the response would have been written with. See WithRequest.
+	OnClientClosed func(r *http.Request, status int)
+
+	// OnViolation, if set, enables strict mode: every response is checked
+	// for protocol mistakes, such as a redirect without a Location header,
+	// and each violation is reported to OnViolation. Use FailTest to fail
+	// tests on violations.
+	OnViolation func(v Violation)
+
+	// Messages, if set, holds the messages written when no data is given,
+	// keyed by status code, such as "404", and the messages of errors with
+	// an error code, keyed by error code. The language of request-aware
+	// responses is negotiated from the Accept-Language header of the
+	// request. Status codes without a message get the registry text.
+	Messages *Messages
+
+	// OnIncident, if set, enables safe server errors: 5xx responses whose
+	// data is an error without an error code are written with the status
+	// text and an incident ID instead of the error, and the error is
+	// reported to OnIncident with the same ID. Use LogIncident to log them.
+	OnIncident func(i Incident)
+
+	// IncidentStack adds the stack of the goroutine that wrote the response
+	// to incidents.
+	IncidentStack bool
+
+	// RevealIncidents writes the error, its causes and stack in incident
+	// responses, and the error in other 5xx responses. It is meant for
+	// development only.
+	RevealIncidents bool
+
+	// ErrorFormatter renders errors passed as data, other than errors with
+	// an error code and errors that implement json.Marshaler. If nil,
+	// DefaultErrorFormatter is used, except in 5xx responses, which are
+	// written with the status text unless RevealIncidents is set.
+	ErrorFormatter ErrorFormatter
+
+	// Observer, if set, is notified of every response, with its status
+	// code, size, encode duration and whether it was written.
+	Observer Observer
+
+	// ValidateRaw checks that data that is already encoded, such as Raw,
+	// json.RawMessage or the output of a json.Marshaler, is valid JSON
+	// before it is written verbatim. Invalid data panics with ErrInvalidRaw,
+	// like data that cannot be encoded.
+	ValidateRaw bool
+
+	// Canonical writes responses in the canonical JSON form of RFC 8785,
+	// without a trailing newline, so that equal responses have the same
+	// bytes, for clients that hash or sign them. See Canonical. Data that
+	// cannot be canonicalized panics, like data that cannot be encoded.
+	Canonical bool
+
+	// ContentDigest lists the algorithms of the Content-Digest header (RFC
+	// 9530) added to responses with a body, such as DigestSHA256. The
+	// digest covers the body as written.
+	ContentDigest []string
+
+	// Signer, if set, signs responses with a body with HTTP Message
+	// Signatures (RFC 9421). If it covers the content-digest component, a
+	// Content-Digest header is added, with DigestSHA256 if ContentDigest is
+	// empty. A response that cannot be signed panics, like data that
+	// cannot be encoded.
+	Signer *MessageSigner
+}
+
+var _ Responder = (*JSONResponder)(nil)
+
+// DefaultResponder is the JSONResponder used by the package-level functions.
+var DefaultResponder = &JSONResponder{}
+
+// responderOf returns j, or DefaultResponder if j is nil. Middleware with a
+// Responder field answer requests through it.
+func responderOf(j *JSONResponder) *JSONResponder {
+	if j == nil {
+		return DefaultResponder
+	}
+	return j
+}
+
+// respond writes a JSON-encoded body to http.ResponseWriter. See the
+// package-level respond function for how data is handled.
+//
+// Responses with a status code that does not allow a body, according to the
+// status code registry, are written without one.
+//
+// If w is tracked, the response is not written if one was already sent. If
+// w is request-aware, the response is not written once the request context
+// is done, and writing is aborted if it ends while encoding. The request ID
+// and trace ID of a request-aware response are added to its meta field and
+// headers, see Correlator.
+func (j *JSONResponder) respond(w http.ResponseWriter, statusCode int, meta map[string]interface{}, data ...interface{}) {
+	j.write(w, statusCode, meta, false, data)
+}
+
+// write writes a response like respond. If problem is set, data holds a
+// *Problem, which is written as application/problem+json instead of the
+// default JSON structure.
+func (j *JSONResponder) write(w http.ResponseWriter, statusCode int, meta map[string]interface{}, problem bool, data []interface{}) {
+	req := requestOf(w)
+
+	o := Observation{Request: req, Status: statusCode}
+	if j.Observer != nil {
+		defer j.observe(&o, data)
+	}
+
+	if tw := trackerOf(w); tw != nil {
+		if !tw.begin() {
+			o.ErrorKind = ErrorKindDuplicate
+			return
+		}
+		defer tw.end()
+	}
+
+	if j.OnViolation != nil {
+		j.check(w, statusCode, data)
+	}
+
+	if req != nil && req.Context().Err() != nil {
+		o.closed(req)
+		j.clientClosed(req, statusCode)
+		return
+	}
+
+	if req != nil {
+		meta = correlate(w, req, meta)
+	}
+
+	info, _ := LookupStatus(statusCode)
+	if !info.BodyAllowed {
+		w.WriteHeader(statusCode)
+		o.Written = true
+		return
+	}
+
+	// Responses with the status text as data and no meta have a precomputed
+	// body, unless they are canonical.
+	var body []byte
+	var value interface{}
+	if problem {
+		value = data[0]
+	} else if len(data) == 0 {
+		if text := j.statusText(w, req, info); text != info.Text || len(meta) > 0 || j.Canonical {
+			value = text
+		} else {
+			body = defaultBody(info)
+		}
+	} else if e, ok := codedError(data[0]); ok {
+		o.Err, o.ErrorCode = data[0].(error), e.Code.Code
+		value = j.localize(w, req, e)
+	} else if err, ok := isPlainError(data[0]); ok {
+		o.Err = err
+		switch {
+		case info.Class != ClassServerError:
+			value = j.formatError(err)
+		case j.OnIncident != nil:
+			value, meta = j.incident(w, req, info, err, meta)
+		case j.ErrorFormatter == nil && !j.RevealIncidents:
+			// Server errors are not written to clients by default, as
+			// their messages may reveal internal details.
+			value = j.statusText(w, req, info)
+		default:
+			value = j.formatError(err)
+		}
+	} else {
+		value = data[0]
+	}
+
+	// The body is encoded before the header is written, so that an encoding
+	// error does not leave a partial response, and so that the encode time
+	// can be sent in the Server-Timing header.
+	timings := serverTimingsOf(req)
+	var start time.Time
+	if j.Observer != nil || timings != nil {
+		start = time.Now()
+	}
+
+	if body == nil {
+		eb := getEncodeBuffer()
+		defer putEncodeBuffer(eb)
+		var err error
+		if problem {
+			err = j.encodeProblem(eb, value)
+		} else {
+			err = j.encode(eb, statusCode, value, meta)
+		}
+		if err != nil {
+			o.ErrorKind = ErrorKindEncode
+			panic(err)
+		}
+		body = eb.Bytes()
+	}
+	if timings != nil {
+		timings.Add(ServerTimingEncode, time.Since(start))
+	}
+
+	o.ContentType = "application/json; charset=UTF-8"
+	if problem {
+		o.ContentType = "application/problem+json; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", o.ContentType)
+	if len(j.ContentDigest) > 0 || j.Signer != nil {
+		if err := j.sign(w, req, statusCode, body); err != nil {
+			o.ErrorKind = ErrorKindSign
+			panic(err)
+		}
+	}
+	w.WriteHeader(statusCode)
+
+	cw := &contextWriter{w: w}
+	if req != nil {
+		cw.ctx = req.Context()
+	}
+	_, err := cw.Write(body)
+	if j.Observer != nil {
+		o.EncodeDuration = time.Since(start)
+	}
+	o.Bytes = cw.n
+
+	switch {
+	case err == nil:
+		o.Written = true
+	case req == nil:
+		o.ErrorKind = ErrorKindWrite
+		panic(err)
+	case req.Context().Err() != nil:
+		o.closed(req)
+		j.clientClosed(req, statusCode)
+	default:
+		o.ErrorKind = ErrorKindWrite
+		j.clientClosed(req, statusCode)
+	}
+}
+
+// statusText returns the message of responses with the status code of info
+// that have no data.
+func (j *JSONResponder) statusText(w http.ResponseWriter, req *http.Request, info StatusInfo) string {
+	if j.Messages == nil {
+		return info.Text
+	}
+	if message, ok := j.message(w, req, strconv.Itoa(info.Code), nil); ok {
+		return message
+	}
+	return info.Text
+}
+
+// Respond writes data with a custom status.
+func (j *JSONResponder) Respond(w http.ResponseWriter, status int, data ...interface{}) {
+	j.respond(w, status, nil, data...)
+}
+
+// Continue writes data with status code 100.
+func (j *JSONResponder) Continue(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusContinue, nil, data...)
+}
+
+// SwitchingProtocols writes data with status code 101.
+func (j *JSONResponder) SwitchingProtocols(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusSwitchingProtocols, nil, data...)
+}
+
+// Processing writes data with status code 102.
+func (j *JSONResponder) Processing(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusProcessing, nil, data...)
+}
+
+// OK writes data with status code 200.
+func (j *JSONResponder) OK(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusOK, nil, data...)
+}
+
+// Created writes data with status code 201.
+func (j *JSONResponder) Created(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusCreated, nil, data...)
+}
+
+// Accepted writes data with status code 202.
+func (j *JSONResponder) Accepted(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusAccepted, nil, data...)
+}
+
+// NonAuthoritativeInfo writes data with status code 203.
+func (j *JSONResponder) NonAuthoritativeInfo(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNonAuthoritativeInfo, nil, data...)
+}
+
+// NoContent writes data with status code 204.
+func (j *JSONResponder) NoContent(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNoContent, nil, data...)
+}
+
+// ResetContent writes data with status code 205.
+func (j *JSONResponder) ResetContent(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusResetContent, nil, data...)
+}
+
+// PartialContent writes data with status code 206.
+func (j *JSONResponder) PartialContent(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusPartialContent, nil, data...)
+}
+
+// MultiStatus writes data with status code 207.
+func (j *JSONResponder) MultiStatus(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusMultiStatus, nil, data...)
+}
+
+// AlreadyReported writes data with status code 208.
+func (j *JSONResponder) AlreadyReported(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusAlreadyReported, nil, data...)
+}
+
+// IMUsed writes data with status code 226.
+func (j *JSONResponder) IMUsed(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusIMUsed, nil, data...)
+}
+
+// MultipleChoices writes data with status code 300.
+func (j *JSONResponder) MultipleChoices(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusMultipleChoices, nil, data...)
+}
+
+// MovedPermanently writes data with status code 301.
+func (j *JSONResponder) MovedPermanently(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusMovedPermanently, nil, data...)
+}
+
+// Found writes data with status code 302.
+func (j *JSONResponder) Found(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusFound, nil, data...)
+}
+
+// SeeOther writes data with status code 303.
+func (j *JSONResponder) SeeOther(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusSeeOther, nil, data...)
+}
+
+// NotModified writes data with status code 304.
+func (j *JSONResponder) NotModified(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNotModified, nil, data...)
+}
+
+// UseProxy writes data with status code 305.
+func (j *JSONResponder) UseProxy(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusUseProxy, nil, data...)
+}
+
+// SwitchProxy writes data with status code 306.
+func (j *JSONResponder) SwitchProxy(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, statusSwitchProxy, nil, data...)
+}
+
+// TemporaryRedirect writes data with status code 307.
+func (j *JSONResponder) TemporaryRedirect(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusTemporaryRedirect, nil, data...)
+}
+
+// PermanentRedirect writes data with status code 308.
+func (j *JSONResponder) PermanentRedirect(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusPermanentRedirect, nil, data...)
+}
+
+// BadRequest writes data with status code 400.
+func (j *JSONResponder) BadRequest(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusBadRequest, nil, data...)
+}
+
+// Unauthorized writes data with status code 401.
+func (j *JSONResponder) Unauthorized(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusUnauthorized, nil, data...)
+}
+
+// PaymentRequired writes data with status code 402.
+func (j *JSONResponder) PaymentRequired(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusPaymentRequired, nil, data...)
+}
+
+// Forbidden writes data with status code 403.
+func (j *JSONResponder) Forbidden(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusForbidden, nil, data...)
+}
+
+// NotFound writes data with status code 404.
+func (j *JSONResponder) NotFound(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNotFound, nil, data...)
+}
+
+// MethodNotAllowed writes data with status code 405.
+func (j *JSONResponder) MethodNotAllowed(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusMethodNotAllowed, nil, data...)
+}
+
+// NotAcceptable writes data with status code 406.
+func (j *JSONResponder) NotAcceptable(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNotAcceptable, nil, data...)
+}
+
+// ProxyAuthenticationRequired writes data with status code 407.
+func (j *JSONResponder) ProxyAuthenticationRequired(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusProxyAuthRequired, nil, data...)
+}
+
+// RequestTimeout writes data with status code 408.
+func (j *JSONResponder) RequestTimeout(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusRequestTimeout, nil, data...)
+}
+
+// Conflict writes data with status code 409.
+func (j *JSONResponder) Conflict(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusConflict, nil, data...)
+}
+
+// Gone writes data with status code 410.
+func (j *JSONResponder) Gone(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusGone, nil, data...)
+}
+
+// LengthRequired writes data with status code 411.
+func (j *JSONResponder) LengthRequired(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusLengthRequired, nil, data...)
+}
+
+// PreconditionFailed writes data with status code 412.
+func (j *JSONResponder) PreconditionFailed(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusPreconditionFailed, nil, data...)
+}
+
+// PayloadTooLarge writes data with status code 413.
+func (j *JSONResponder) PayloadTooLarge(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusRequestEntityTooLarge, nil, data...)
+}
+
+// URITooLong writes data with status code 414.
+func (j *JSONResponder) URITooLong(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusRequestURITooLong, nil, data...)
+}
+
+// UnsupportedMediaType writes data with status code 415.
+func (j *JSONResponder) UnsupportedMediaType(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusUnsupportedMediaType, nil, data...)
+}
+
+// RangeNotSatisfiable writes data with status code 416.
+func (j *JSONResponder) RangeNotSatisfiable(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusRequestedRangeNotSatisfiable, nil, data...)
+}
+
+// ExpectationFailed writes data with status code 417.
+func (j *JSONResponder) ExpectationFailed(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusExpectationFailed, nil, data...)
+}
+
+// Teapot writes data with status code 418.
+func (j *JSONResponder) Teapot(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusTeapot, nil, data...)
+}
+
+// MisdirectedRequest writes data with status code 421.
+func (j *JSONResponder) MisdirectedRequest(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusMisdirectedRequest, nil, data...)
+}
+
+// UnprocessableEntity writes data with status code 422.
+func (j *JSONResponder) UnprocessableEntity(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusUnprocessableEntity, nil, data...)
+}
+
+// Locked writes data with status code 423.
+func (j *JSONResponder) Locked(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusLocked, nil, data...)
+}
+
+// FailedDependency writes data with status code 424.
+func (j *JSONResponder) FailedDependency(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusFailedDependency, nil, data...)
+}
+
+// UpgradeRequired writes data with status code 426.
+func (j *JSONResponder) UpgradeRequired(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusUpgradeRequired, nil, data...)
+}
+
+// PreconditionRequired writes data with status code 428.
+func (j *JSONResponder) PreconditionRequired(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusPreconditionRequired, nil, data...)
+}
+
+// TooManyRequests writes data with status code 429.
+func (j *JSONResponder) TooManyRequests(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusTooManyRequests, nil, data...)
+}
+
+// RequestHeaderFieldsTooLarge writes data with status code 431.
+func (j *JSONResponder) RequestHeaderFieldsTooLarge(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusRequestHeaderFieldsTooLarge, nil, data...)
+}
+
+// UnavailableForLegalReasons writes data with status code 451.
+func (j *JSONResponder) UnavailableForLegalReasons(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusUnavailableForLegalReasons, nil, data...)
+}
+
+// InternalServerError writes data with status code 500.
+func (j *JSONResponder) InternalServerError(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusInternalServerError, nil, data...)
+}
+
+// NotImplemented writes data with status code 501.
+func (j *JSONResponder) NotImplemented(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNotImplemented, nil, data...)
+}
+
+// BadGateway writes data with status code 502.
+func (j *JSONResponder) BadGateway(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusBadGateway, nil, data...)
+}
+
+// ServiceUnavailable writes data with status code 503.
+func (j *JSONResponder) ServiceUnavailable(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusServiceUnavailable, nil, data...)
+}
+
+// GatewayTimeout writes data with status code 504.
+func (j *JSONResponder) GatewayTimeout(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusGatewayTimeout, nil, data...)
+}
+
+// HTTPVersionNotSupported writes data with status code 505.
+func (j *JSONResponder) HTTPVersionNotSupported(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusHTTPVersionNotSupported, nil, data...)
+}
+
+// VariantAlsoNegotiates writes data with status code 506.
+func (j *JSONResponder) VariantAlsoNegotiates(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusVariantAlsoNegotiates, nil, data...)
+}
+
+// InsufficientStorage writes data with status code 507.
+func (j *JSONResponder) InsufficientStorage(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusInsufficientStorage, nil, data...)
+}
+
+// LoopDetected writes data with status code 508.
+func (j *JSONResponder) LoopDetected(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusLoopDetected, nil, data...)
+}
+
+// NotExtended writes data with status code 510.
+func (j *JSONResponder) NotExtended(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNotExtended, nil, data...)
+}
+
+// NetworkAuthenticationRequired writes data with status code 511.
+func (j *JSONResponder) NetworkAuthenticationRequired(w http.ResponseWriter, data ...interface{}) {
+	j.respond(w, http.StatusNetworkAuthenticationRequired, nil, data...)
+}
@@ -0,0 +1,91 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSONResponderRespond(t *testing.T) {
+	var j Responder = &JSONResponder{}
+	w := httptest.NewRecorder()
+
+	j.Respond(w, http.StatusTeapot, "short and stout")
+
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected status code %#v, got %#v", http.StatusTeapot, w.Code)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "short and stout" {
+		t.Errorf("expected %#v, got %#v", "short and stout", resp.Data)
+	}
+}
+
+func TestJSONResponderStatusCodes(t *testing.T) {
+	j := &JSONResponder{}
+
+	for _, test := range []struct {
+		f    func(w http.ResponseWriter, data ...interface{})
+		code int
+	}{
+		{f: j.OK, code: http.StatusOK},
+		{f: j.SwitchProxy, code: 306},
+		{f: j.ProxyAuthenticationRequired, code: http.StatusProxyAuthRequired},
+		{f: j.PayloadTooLarge, code: http.StatusRequestEntityTooLarge},
+		{f: j.URITooLong, code: http.StatusRequestURITooLong},
+		{f: j.RangeNotSatisfiable, code: http.StatusRequestedRangeNotSatisfiable},
+		{f: j.MisdirectedRequest, code: http.StatusMisdirectedRequest},
+		{f: j.NetworkAuthenticationRequired, code: http.StatusNetworkAuthenticationRequired},
+	} {
+		w := httptest.NewRecorder()
+		test.f(w)
+		if w.Code != test.code {
+			t.Errorf("expected status code %#v, got %#v", test.code, w.Code)
+		}
+	}
+}
+
+type benchmarkUser struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+}
+
+func BenchmarkRespondStatusText(b *testing.B) {
+	w := &probeWriter{header: make(http.Header)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NotFound(w)
+	}
+}
+
+func BenchmarkRespondString(b *testing.B) {
+	w := &probeWriter{header: make(http.Header)}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		BadRequest(w, "invalid email address")
+	}
+}
+
+func BenchmarkRespondStruct(b *testing.B) {
+	w := &probeWriter{header: make(http.Header)}
+	user := &benchmarkUser{ID: 1, Name: "Ada", Email: "ada@example.com", Roles: []string{"admin", "user"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		OK(w, user)
+	}
+}
+
+func BenchmarkRespondRaw(b *testing.B) {
+	w := &probeWriter{header: make(http.Header)}
+	data := Raw(`{"id":1,"name":"Ada","email":"ada@example.com","roles":["admin","user"]}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		OK(w, data)
+	}
+}
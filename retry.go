@@ -0,0 +1,151 @@
+package jsonapi
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// timeNow is used instead of time.Now so tests can control the clock.
+var timeNow = time.Now
+
+// Retry describes when a client may retry a request, and optionally the rate
+// limit that caused it to be rejected.
+type Retry struct {
+	// After is how long the client should wait before retrying. It is sent
+	// in the Retry-After header as delta-seconds.
+	After time.Duration
+
+	// At is the time after which the client may retry. If set, it takes
+	// precedence over After and is sent in the Retry-After header as an
+	// HTTP-date.
+	At time.Time
+
+	// RateLimit, if set, is sent in the RateLimit-Policy and RateLimit
+	// headers.
+	RateLimit *RateLimit
+}
+
+// RateLimit describes a quota policy and how much of it is left, as defined
+// by the IETF RateLimit header fields draft.
+type RateLimit struct {
+	// Policy names the quota policy. It defaults to "default".
+	Policy string
+
+	// Quota is the number of requests allowed per Window.
+	Quota int
+
+	// Window is the time window the quota applies to.
+	Window time.Duration
+
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+
+	// Reset is the time until the quota is restored.
+	Reset time.Duration
+}
+
+// seconds returns the delay before the client may retry, rounded up to whole
+// seconds.
+func (rt Retry) seconds() int64 {
+	d := rt.After
+	if !rt.At.IsZero() {
+		d = rt.At.Sub(timeNow())
+	}
+	return ceilSeconds(d)
+}
+
+// setHeaders sets the Retry-After and RateLimit headers described by rt.
+func (rt Retry) setHeaders(h http.Header) {
+	switch {
+	case !rt.At.IsZero():
+		h.Set("Retry-After", rt.At.UTC().Format(http.TimeFormat))
+	case rt.After > 0:
+		h.Set("Retry-After", strconv.FormatInt(rt.seconds(), 10))
+	}
+
+	if rl := rt.RateLimit; rl != nil {
+		name := quote(rl.policyName())
+		h.Set("RateLimit-Policy", name+";q="+strconv.Itoa(rl.Quota)+";w="+strconv.FormatInt(ceilSeconds(rl.Window), 10))
+		h.Set("RateLimit", name+";r="+strconv.Itoa(rl.Remaining)+";t="+strconv.FormatInt(ceilSeconds(rl.Reset), 10))
+	}
+}
+
+// meta returns the retry information for the meta field of the response.
+func (rt Retry) meta() map[string]interface{} {
+	meta := make(map[string]interface{})
+
+	if !rt.At.IsZero() || rt.After > 0 {
+		at := rt.At
+		if at.IsZero() {
+			at = timeNow().Add(rt.After)
+		}
+		meta["retry_after"] = rt.seconds()
+		meta["retry_at"] = at.UTC().Format(time.RFC3339)
+	}
+
+	if rl := rt.RateLimit; rl != nil {
+		meta["rate_limit"] = map[string]interface{}{
+			"policy":    rl.policyName(),
+			"quota":     rl.Quota,
+			"window":    ceilSeconds(rl.Window),
+			"remaining": rl.Remaining,
+			"reset":     ceilSeconds(rl.Reset),
+		}
+	}
+
+	return meta
+}
+
+func (rl *RateLimit) policyName() string {
+	if rl.Policy == "" {
+		return "default"
+	}
+	return rl.Policy
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// RespondRetry writes data with a custom status, along with the retry
+// guidance in retry. The guidance is sent in the Retry-After and RateLimit
+// headers, and in the meta field of the response.
+func RespondRetry(w http.ResponseWriter, status int, retry Retry, data ...interface{}) {
+	DefaultResponder.RespondRetry(w, status, retry, data...)
+}
+
+// TooManyRequestsRetry writes data with status code 429, along with the
+// retry guidance in retry.
+func TooManyRequestsRetry(w http.ResponseWriter, retry Retry, data ...interface{}) {
+	DefaultResponder.TooManyRequestsRetry(w, retry, data...)
+}
+
+// ServiceUnavailableRetry writes data with status code 503, along with the
+// retry guidance in retry.
+func ServiceUnavailableRetry(w http.ResponseWriter, retry Retry, data ...interface{}) {
+	DefaultResponder.ServiceUnavailableRetry(w, retry, data...)
+}
+
+// RespondRetry writes data with a custom status, along with the retry
+// guidance in retry.
+func (j *JSONResponder) RespondRetry(w http.ResponseWriter, status int, retry Retry, data ...interface{}) {
+	retry.setHeaders(w.Header())
+	j.respond(w, status, retry.meta(), data...)
+}
+
+// TooManyRequestsRetry writes data with status code 429, along with the
+// retry guidance in retry.
+func (j *JSONResponder) TooManyRequestsRetry(w http.ResponseWriter, retry Retry, data ...interface{}) {
+	j.RespondRetry(w, http.StatusTooManyRequests, retry, data...)
+}
+
+// ServiceUnavailableRetry writes data with status code 503, along with the
+// retry guidance in retry.
+func (j *JSONResponder) ServiceUnavailableRetry(w http.ResponseWriter, retry Retry, data ...interface{}) {
+	j.RespondRetry(w, http.StatusServiceUnavailable, retry, data...)
+}
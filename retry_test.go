@@ -0,0 +1,116 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func fixClock(t *testing.T, at time.Time) {
+	t.Helper()
+	timeNow = func() time.Time { return at }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+func TestTooManyRequestsRetryAfterDuration(t *testing.T) {
+	fixClock(t, time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC))
+	w := httptest.NewRecorder()
+
+	TooManyRequestsRetry(w, Retry{After: 1500 * time.Millisecond})
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status code %#v, got %#v", http.StatusTooManyRequests, w.Code)
+	}
+	if h := w.Header().Get("Retry-After"); h != "2" {
+		t.Errorf("expected %#v, got %#v", "2", h)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Meta["retry_after"] != float64(2) {
+		t.Errorf("expected %#v, got %#v", float64(2), resp.Meta["retry_after"])
+	}
+	if resp.Meta["retry_at"] != "2018-06-01T12:00:01Z" {
+		t.Errorf("expected %#v, got %#v", "2018-06-01T12:00:01Z", resp.Meta["retry_at"])
+	}
+}
+
+func TestServiceUnavailableRetryAt(t *testing.T) {
+	fixClock(t, time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC))
+	w := httptest.NewRecorder()
+
+	ServiceUnavailableRetry(w, Retry{At: time.Date(2018, 6, 1, 12, 5, 0, 0, time.UTC)})
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %#v, got %#v", http.StatusServiceUnavailable, w.Code)
+	}
+	if h := w.Header().Get("Retry-After"); h != "Fri, 01 Jun 2018 12:05:00 GMT" {
+		t.Errorf("expected %#v, got %#v", "Fri, 01 Jun 2018 12:05:00 GMT", h)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Meta["retry_after"] != float64(300) {
+		t.Errorf("expected %#v, got %#v", float64(300), resp.Meta["retry_after"])
+	}
+	if resp.Meta["retry_at"] != "2018-06-01T12:05:00Z" {
+		t.Errorf("expected %#v, got %#v", "2018-06-01T12:05:00Z", resp.Meta["retry_at"])
+	}
+}
+
+func TestRetryRateLimitHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	TooManyRequestsRetry(w, Retry{
+		After: 30 * time.Second,
+		RateLimit: &RateLimit{
+			Quota:     100,
+			Window:    time.Minute,
+			Remaining: 0,
+			Reset:     30 * time.Second,
+		},
+	}, "slow down")
+
+	if h := w.Header().Get("RateLimit-Policy"); h != `"default";q=100;w=60` {
+		t.Errorf("expected %#v, got %#v", `"default";q=100;w=60`, h)
+	}
+	if h := w.Header().Get("RateLimit"); h != `"default";r=0;t=30` {
+		t.Errorf("expected %#v, got %#v", `"default";r=0;t=30`, h)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "slow down" {
+		t.Errorf("expected %#v, got %#v", "slow down", resp.Data)
+	}
+	rl, _ := resp.Meta["rate_limit"].(map[string]interface{})
+	if rl["quota"] != float64(100) || rl["remaining"] != float64(0) || rl["reset"] != float64(30) {
+		t.Errorf("expected rate limit in meta, got %#v", resp.Meta["rate_limit"])
+	}
+}
+
+func TestRetryWithoutGuidance(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	TooManyRequestsRetry(w, Retry{})
+
+	if h := w.Header().Get("Retry-After"); h != "" {
+		t.Errorf("expected %#v, got %#v", "", h)
+	}
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Meta != nil {
+		t.Errorf("expected %#v, got %#v", nil, resp.Meta)
+	}
+}
@@ -0,0 +1,202 @@
+package jsonapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the Server-Timing metrics recorded by this package.
+const (
+	// ServerTimingEncode is the time spent encoding a response written with
+	// a JSONResponder.
+	ServerTimingEncode = "encode"
+
+	// ServerTimingTotal is the time from the start of the request to the
+	// response header.
+	ServerTimingTotal = "total"
+)
+
+// ServerTimings collects the Server-Timing metrics of a request, such as the
+// time spent in the database, in a cache or rendering. Its methods may be
+// called on a nil *ServerTimings, in which case they do nothing, so handlers
+// need not check whether the request is timed.
+//
+// A ServerTimings is safe for concurrent use.
+type ServerTimings struct {
+	start time.Time
+
+	mu      sync.Mutex
+	metrics []serverTiming
+
+	// sent is the number of metrics sent in the response header.
+	sent int
+}
+
+type serverTiming struct {
+	name string
+	dur  time.Duration
+}
+
+type serverTimingsKey struct{}
+
+// ServerTiming returns a handler that collects the Server-Timing metrics
+// of every request handled by next, see ServerTimingsFromContext, and sends
+// them in the Server-Timing header of the response, with the total time
+// until the header was written. Metrics recorded after the header was
+// written, as in streamed responses, are sent as a trailer. The responses
+// written by next are made request-aware, so that the encode time of
+// responses written with a JSONResponder is recorded automatically.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &ServerTimings{start: time.Now()}
+		r = r.WithContext(context.WithValue(r.Context(), serverTimingsKey{}, t))
+
+		tw := &timingWriter{ResponseWriter: w, timings: t}
+		next.ServeHTTP(WithRequest(tw, r), r)
+
+		if !tw.wroteHeader {
+			tw.writeTimings()
+			return
+		}
+		if trailer := t.pending(); trailer != "" {
+			w.Header().Set(http.TrailerPrefix+"Server-Timing", trailer)
+		}
+	})
+}
+
+// ServerTimingsFromContext returns the Server-Timing metrics of the request
+// with the context ctx, or nil if it is not handled by ServerTiming.
+func ServerTimingsFromContext(ctx context.Context) *ServerTimings {
+	t, _ := ctx.Value(serverTimingsKey{}).(*ServerTimings)
+	return t
+}
+
+// serverTimingsOf returns the Server-Timing metrics of r, which may be nil.
+func serverTimingsOf(r *http.Request) *ServerTimings {
+	if r == nil {
+		return nil
+	}
+	return ServerTimingsFromContext(r.Context())
+}
+
+// Add records the metric name with the duration d. Names should be HTTP
+// tokens, such as "db"; other characters are replaced with "_".
+func (t *ServerTimings) Add(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.metrics = append(t.metrics, serverTiming{name: timingName(name), dur: d})
+	t.mu.Unlock()
+}
+
+// Start starts timing the metric name, and returns a function that records
+// it when called:
+//
+//	defer jsonapi.ServerTimingsFromContext(ctx).Start("db")()
+func (t *ServerTimings) Start(name string) (stop func()) {
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.Add(name, time.Since(start))
+	}
+}
+
+// header returns the value of the Server-Timing header with the metrics
+// recorded so far and the total time, and marks them as sent.
+func (t *ServerTimings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := append(t.metrics[:len(t.metrics):len(t.metrics)], serverTiming{name: ServerTimingTotal, dur: time.Since(t.start)})
+	t.sent = len(t.metrics)
+	return formatServerTimings(metrics)
+}
+
+// pending returns the value of the Server-Timing trailer with the metrics
+// recorded after the header was sent, or "".
+func (t *ServerTimings) pending() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	metrics := t.metrics[t.sent:]
+	t.sent = len(t.metrics)
+	return formatServerTimings(metrics)
+}
+
+// formatServerTimings returns metrics as the value of a Server-Timing
+// header, such as "db;dur=12.5, encode;dur=0.2".
+func formatServerTimings(metrics []serverTiming) string {
+	var b strings.Builder
+	for i, m := range metrics {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(m.name)
+		b.WriteString(";dur=")
+		b.WriteString(strconv.FormatFloat(float64(m.dur.Round(time.Microsecond))/float64(time.Millisecond), 'f', -1, 64))
+	}
+	return b.String()
+}
+
+// timingName returns name with the characters that are not allowed in an
+// HTTP token replaced with "_".
+func timingName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// timingWriter is an http.ResponseWriter that adds the Server-Timing header
+// when the response header is written.
+type timingWriter struct {
+	http.ResponseWriter
+	timings     *ServerTimings
+	wroteHeader bool
+}
+
+func (tw *timingWriter) WriteHeader(code int) {
+	// Informational responses may precede the final response.
+	if !tw.wroteHeader && (code < 100 || code >= 200 || code == http.StatusSwitchingProtocols) {
+		tw.writeTimings()
+		tw.wroteHeader = true
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timingWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (tw *timingWriter) Flush() {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for
+// http.ResponseController.
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+func (tw *timingWriter) writeTimings() {
+	tw.Header().Set("Server-Timing", tw.timings.header())
+}
@@ -0,0 +1,90 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestServerTiming(t *testing.T) {
+	h := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timings := ServerTimingsFromContext(r.Context())
+		timings.Add("cache", 1500*time.Microsecond)
+		stop := timings.Start("db")
+		stop()
+		OK(w, "hello")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	header := w.Header().Get("Server-Timing")
+	expected := regexp.MustCompile(`^cache;dur=1\.5, db;dur=[0-9.]+, encode;dur=[0-9.]+, total;dur=[0-9.]+$`)
+	if !expected.MatchString(header) {
+		t.Errorf("expected %s, got %#v", expected, header)
+	}
+}
+
+func TestServerTimingTrailer(t *testing.T) {
+	h := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timings := ServerTimingsFromContext(r.Context())
+		timings.Add("db", 2*time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		http.NewResponseController(w).Flush()
+		w.Write([]byte("streamed"))
+		timings.Add("render", 3*time.Millisecond)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	resp := w.Result()
+
+	if header := resp.Header.Get("Server-Timing"); !regexp.MustCompile(`^db;dur=2, total;dur=[0-9.]+$`).MatchString(header) {
+		t.Errorf("expected db and total in header, got %#v", header)
+	}
+	if trailer := resp.Trailer.Get("Server-Timing"); trailer != "render;dur=3" {
+		t.Errorf("expected %#v, got %#v", "render;dur=3", trailer)
+	}
+}
+
+func TestServerTimingWithoutResponse(t *testing.T) {
+	h := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServerTimingsFromContext(r.Context()).Add("work", time.Millisecond)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if header := w.Header().Get("Server-Timing"); !regexp.MustCompile(`^work;dur=1, total;dur=[0-9.]+$`).MatchString(header) {
+		t.Errorf("expected work and total in header, got %#v", header)
+	}
+}
+
+func TestServerTimingsNil(t *testing.T) {
+	timings := ServerTimingsFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	if timings != nil {
+		t.Errorf("expected %#v, got %#v", nil, timings)
+	}
+
+	// Must not panic.
+	timings.Add("db", time.Millisecond)
+	timings.Start("db")()
+}
+
+func TestTimingName(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		expected string
+	}{
+		{"db", "db"},
+		{"db query", "db_query"},
+		{"cache;dur=1", "cache_dur_1"},
+		{"", "_"},
+	} {
+		if name := timingName(test.name); name != test.expected {
+			t.Errorf("expected %#v, got %#v", test.expected, name)
+		}
+	}
+}
@@ -0,0 +1,730 @@
+package jsonapi
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithms of HTTP Message Signatures (RFC 9421) supported by
+// MessageSigner and MessageVerifier.
+const (
+	SignatureHMACSHA256 = "hmac-sha256"
+	SignatureEd25519    = "ed25519"
+)
+
+// DefaultSignatureComponents are the components signed by a MessageSigner
+// without Components: the status code, content type and content digest of
+// the response.
+var DefaultSignatureComponents = []string{"@status", "content-type", "content-digest"}
+
+// ErrSignature is returned when the signature of a message is missing,
+// malformed, expired or does not match.
+var ErrSignature = errors.New("jsonapi: invalid message signature")
+
+// MessageSigner signs responses with HTTP Message Signatures (RFC 9421), in
+// the Signature-Input and Signature headers. Set it as the Signer of a
+// JSONResponder to sign its responses.
+type MessageSigner struct {
+	// Label is the label of the signature in the headers. If empty, "sig1"
+	// is used.
+	Label string
+
+	// KeyID identifies the key to verifiers, in the keyid parameter.
+	KeyID string
+
+	// Key is the key responses are signed with: a []byte secret for
+	// SignatureHMACSHA256, or an ed25519.PrivateKey for SignatureEd25519.
+	Key interface{}
+
+	// Components are the covered components, such as "@status", header
+	// names such as "content-digest", and components of the request, such
+	// as "@method;req" or "@path;req". If nil,
+	// DefaultSignatureComponents is used.
+	Components []string
+
+	// Expires, if positive, is how long signatures are valid, in the
+	// expires parameter.
+	Expires time.Duration
+
+	// Tag, if set, is the application-specific tag parameter.
+	Tag string
+
+	// Now returns the creation time of signatures. If nil, time.Now is
+	// used.
+	Now func() time.Time
+}
+
+// SignResponse signs a response with the status code status and the header
+// header, and adds the signature to header. r is the request of the
+// response, for components of the request, and may be nil otherwise.
+// Covered header fields must be set before the response is signed.
+func (s *MessageSigner) SignResponse(status int, header http.Header, r *http.Request) error {
+	alg, err := signatureAlgorithm(s.Key)
+	if err != nil {
+		return err
+	}
+
+	names := s.Components
+	if names == nil {
+		names = DefaultSignatureComponents
+	}
+	components := make([]component, len(names))
+	for i, name := range names {
+		if components[i], err = parseComponent(name); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	created := now()
+
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, c := range components {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(c.String())
+	}
+	b.WriteString(");created=")
+	b.WriteString(strconv.FormatInt(created.Unix(), 10))
+	if s.Expires > 0 {
+		b.WriteString(";expires=")
+		b.WriteString(strconv.FormatInt(created.Add(s.Expires).Unix(), 10))
+	}
+	for _, p := range [][2]string{{"keyid", s.KeyID}, {"alg", alg}, {"tag", s.Tag}} {
+		if p[1] == "" {
+			continue
+		}
+		v, err := sfString(p[1])
+		if err != nil {
+			return err
+		}
+		b.WriteString(";" + p[0] + "=" + v)
+	}
+	params := b.String()
+
+	base, err := signatureBase(components, params, func(c component) (string, error) {
+		return componentValue(c, status, header, r)
+	})
+	if err != nil {
+		return err
+	}
+	sig, err := sign(s.Key, []byte(base))
+	if err != nil {
+		return err
+	}
+
+	label := s.Label
+	if label == "" {
+		label = "sig1"
+	}
+	header.Set("Signature-Input", label+"="+params)
+	header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// covers reports whether s signs the component name.
+func (s *MessageSigner) covers(name string) bool {
+	names := s.Components
+	if names == nil {
+		names = DefaultSignatureComponents
+	}
+	return slices.Contains(names, name)
+}
+
+// sign adds the Content-Digest header and the signature of a response with
+// the body body to w.
+func (j *JSONResponder) sign(w http.ResponseWriter, req *http.Request, status int, body []byte) error {
+	if len(j.ContentDigest) > 0 || j.Signer != nil && j.Signer.covers("content-digest") {
+		digest, err := ContentDigest(body, j.ContentDigest...)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Digest", digest)
+	}
+	if j.Signer == nil {
+		return nil
+	}
+	return j.Signer.SignResponse(status, w.Header(), req)
+}
+
+// MessageVerifier verifies the HTTP Message Signatures (RFC 9421) of
+// responses, such as those signed by a MessageSigner, in tests and clients.
+type MessageVerifier struct {
+	// Label is the label of the signature to verify. If empty, "sig1" is
+	// used.
+	Label string
+
+	// Keys are the keys of the signers by key ID: []byte secrets for
+	// SignatureHMACSHA256, or ed25519.PublicKey for SignatureEd25519.
+	Keys map[string]interface{}
+
+	// Required are the components the signature must cover. If nil, the
+	// status code and content digest must be covered, so that the body is
+	// verified too.
+	Required []string
+
+	// MaxAge, if positive, is the maximum age of signatures, from their
+	// created parameter.
+	MaxAge time.Duration
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// VerifyResponse verifies the signature of res. body is the body of res,
+// which is verified against its Content-Digest header if the signature
+// covers it. Components of the request are taken from res.Request.
+func (v *MessageVerifier) VerifyResponse(res *http.Response, body []byte) error {
+	label := v.Label
+	if label == "" {
+		label = "sig1"
+	}
+
+	inputs, err := parseDictionary(strings.Join(res.Header.Values("Signature-Input"), ", "))
+	if err != nil {
+		return fmt.Errorf("%w: Signature-Input: %v", ErrSignature, err)
+	}
+	signatures, err := parseDictionary(strings.Join(res.Header.Values("Signature"), ", "))
+	if err != nil {
+		return fmt.Errorf("%w: Signature: %v", ErrSignature, err)
+	}
+	input, ok := findMember(inputs, label)
+	if !ok || input.items == nil {
+		return fmt.Errorf("%w: no signature %s", ErrSignature, label)
+	}
+	signature, ok := findMember(signatures, label)
+	if !ok {
+		return fmt.Errorf("%w: no signature %s", ErrSignature, label)
+	}
+	sig, ok := signature.byteSequence()
+	if !ok {
+		return fmt.Errorf("%w: signature %s is not a byte sequence", ErrSignature, label)
+	}
+
+	components := make([]component, len(input.items))
+	for i, item := range input.items {
+		if item.kind != '"' {
+			return fmt.Errorf("%w: component %s is not a string", ErrSignature, item.value)
+		}
+		c := component{name: item.value}
+		for k, p := range item.params {
+			if k != "req" || p != "?1" {
+				return fmt.Errorf("%w: unsupported component parameter %s", ErrSignature, k)
+			}
+			c.req = true
+		}
+		components[i] = c
+	}
+
+	required := v.Required
+	if required == nil {
+		required = []string{"@status", "content-digest"}
+	}
+	for _, name := range required {
+		c, err := parseComponent(name)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(components, c) {
+			return fmt.Errorf("%w: %s is not covered", ErrSignature, c)
+		}
+	}
+
+	key, ok := v.Keys[input.params["keyid"]]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrSignature, input.params["keyid"])
+	}
+	alg, err := signatureAlgorithm(key)
+	if err != nil {
+		return err
+	}
+	if a, ok := input.params["alg"]; ok && a != alg {
+		return fmt.Errorf("%w: algorithm %s does not match the key", ErrSignature, a)
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if created, ok := input.params["created"]; ok {
+		t, err := strconv.ParseInt(created, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: created %s", ErrSignature, created)
+		}
+		if v.MaxAge > 0 && now().Sub(time.Unix(t, 0)) > v.MaxAge {
+			return fmt.Errorf("%w: signature is older than %v", ErrSignature, v.MaxAge)
+		}
+	} else if v.MaxAge > 0 {
+		return fmt.Errorf("%w: signature has no creation time", ErrSignature)
+	}
+	if expires, ok := input.params["expires"]; ok {
+		t, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: expires %s", ErrSignature, expires)
+		}
+		if now().Unix() > t {
+			return fmt.Errorf("%w: signature expired", ErrSignature)
+		}
+	}
+
+	base, err := signatureBase(components, input.value, func(c component) (string, error) {
+		return componentValue(c, res.StatusCode, res.Header, res.Request)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+	if !verify(key, []byte(base), sig) {
+		return fmt.Errorf("%w: signature %s does not match", ErrSignature, label)
+	}
+
+	if slices.Contains(components, component{name: "content-digest"}) {
+		return VerifyContentDigest(strings.Join(res.Header.Values("Content-Digest"), ", "), body)
+	}
+	return nil
+}
+
+// component is a covered component of a signature.
+type component struct {
+	name string
+
+	// req is set for components of the request of a response.
+	req bool
+}
+
+// parseComponent parses a component such as "content-type" or
+// "@method;req".
+func parseComponent(s string) (component, error) {
+	name, flags, _ := strings.Cut(s, ";")
+	c := component{name: name}
+	if flags != "" {
+		if flags != "req" {
+			return c, fmt.Errorf("jsonapi: unsupported signature component %q", s)
+		}
+		c.req = true
+	}
+	if name == "" || strings.ToLower(name) != name || strings.ContainsFunc(name, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || r == '"' || r == '\\' || r == ';'
+	}) {
+		return c, fmt.Errorf("jsonapi: invalid signature component %q", s)
+	}
+	return c, nil
+}
+
+// String returns the component identifier of c.
+func (c component) String() string {
+	if c.req {
+		return `"` + c.name + `";req`
+	}
+	return `"` + c.name + `"`
+}
+
+// componentValue returns the value of the component c of a response with
+// the status code status and the header header to the request r.
+func componentValue(c component, status int, header http.Header, r *http.Request) (string, error) {
+	if c.req {
+		if r == nil {
+			return "", fmt.Errorf("jsonapi: component %s of a response without request", c)
+		}
+		if strings.HasPrefix(c.name, "@") {
+			return requestComponent(r, c.name)
+		}
+		return fieldValue(r.Header, c.name)
+	}
+
+	switch {
+	case c.name == "@status":
+		return strconv.Itoa(status), nil
+	case strings.HasPrefix(c.name, "@"):
+		return "", fmt.Errorf("jsonapi: unsupported component %s of a response", c)
+	}
+	return fieldValue(header, c.name)
+}
+
+// requestComponent returns the value of the derived component name of r.
+func requestComponent(r *http.Request, name string) (string, error) {
+	scheme := strings.ToLower(r.URL.Scheme)
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	authority := r.Host
+	if authority == "" {
+		authority = r.URL.Host
+	}
+	authority = strings.ToLower(authority)
+
+	switch name {
+	case "@method":
+		return r.Method, nil
+	case "@scheme":
+		return scheme, nil
+	case "@authority":
+		return authority, nil
+	case "@target-uri":
+		return scheme + "://" + authority + r.URL.RequestURI(), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		if path := r.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	return "", fmt.Errorf("jsonapi: unsupported component %q of a request", name)
+}
+
+// fieldValue returns the value of the header field name, with the values of
+// its field lines trimmed and joined.
+func fieldValue(header http.Header, name string) (string, error) {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("jsonapi: missing header %s", name)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// signatureBase returns the signature base of the components with the
+// values returned by value, and the signature parameters params.
+func signatureBase(components []component, params string, value func(c component) (string, error)) (string, error) {
+	var b strings.Builder
+	for i, c := range components {
+		if slices.Contains(components[:i], c) {
+			return "", fmt.Errorf("jsonapi: duplicate component %s", c)
+		}
+		v, err := value(c)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(c.String())
+		b.WriteString(": ")
+		b.WriteString(v)
+		b.WriteByte('\n')
+	}
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(params)
+	return b.String(), nil
+}
+
+// signatureAlgorithm returns the signature algorithm of key.
+func signatureAlgorithm(key interface{}) (string, error) {
+	switch key := key.(type) {
+	case []byte:
+		if len(key) == 0 {
+			return "", errors.New("jsonapi: empty HMAC key")
+		}
+		return SignatureHMACSHA256, nil
+	case ed25519.PrivateKey:
+		if len(key) != ed25519.PrivateKeySize {
+			return "", errors.New("jsonapi: invalid Ed25519 private key")
+		}
+		return SignatureEd25519, nil
+	case ed25519.PublicKey:
+		if len(key) != ed25519.PublicKeySize {
+			return "", errors.New("jsonapi: invalid Ed25519 public key")
+		}
+		return SignatureEd25519, nil
+	}
+	return "", fmt.Errorf("jsonapi: unsupported signature key %T", key)
+}
+
+func sign(key interface{}, base []byte) ([]byte, error) {
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(base)
+		return mac.Sum(nil), nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, base), nil
+	}
+	return nil, fmt.Errorf("jsonapi: cannot sign with %T", key)
+}
+
+func verify(key interface{}, base, sig []byte) bool {
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(base)
+		return hmac.Equal(mac.Sum(nil), sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, base, sig)
+	}
+	return false
+}
+
+// sfString returns s as a structured field string (RFC 8941).
+func sfString(s string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f {
+			return "", fmt.Errorf("jsonapi: invalid character in parameter %q", s)
+		}
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
+
+// dictMember is a member of a structured field dictionary (RFC 8941).
+type dictMember struct {
+	key string
+
+	// value is the serialized value of the member, with its parameters.
+	value string
+
+	// kind is the kind of the value: '"' for strings, ':' for byte
+	// sequences, '(' for inner lists, '?' for booleans, '0' for numbers
+	// and 't' for tokens.
+	kind  byte
+	bare  string
+	items []sfItem
+
+	params map[string]string
+}
+
+// sfItem is an item of an inner list. Its value is unquoted or decoded.
+type sfItem struct {
+	kind   byte
+	value  string
+	params map[string]string
+}
+
+// byteSequence returns the value of m if it is a byte sequence.
+func (m dictMember) byteSequence() ([]byte, bool) {
+	if m.kind != ':' {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(m.bare)
+	return b, err == nil
+}
+
+func findMember(members []dictMember, key string) (dictMember, bool) {
+	// The last member with a key overrides the others.
+	for i := len(members) - 1; i >= 0; i-- {
+		if members[i].key == key {
+			return members[i], true
+		}
+	}
+	return dictMember{}, false
+}
+
+// parseDictionary parses the structured field dictionary s, with the
+// subset of RFC 8941 used by Content-Digest and HTTP Message Signatures.
+func parseDictionary(s string) ([]dictMember, error) {
+	p := &sfParser{s: strings.TrimSpace(s)}
+	var members []dictMember
+	for p.i < len(p.s) {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		m := dictMember{key: key}
+
+		start := p.i
+		if p.consume('=') {
+			start = p.i
+			if p.peek() == '(' {
+				m.kind = '('
+				if m.items, err = p.innerList(); err != nil {
+					return nil, err
+				}
+			} else if m.kind, m.bare, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		} else {
+			m.kind, m.bare = '?', "?1"
+		}
+		if m.params, err = p.params(); err != nil {
+			return nil, err
+		}
+		m.value = p.s[start:p.i]
+		members = append(members, m)
+
+		p.skip(" \t")
+		if p.i == len(p.s) {
+			break
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("expected ',' at offset %d", p.i)
+		}
+		p.skip(" \t")
+		if p.i == len(p.s) {
+			return nil, errors.New("trailing ','")
+		}
+	}
+	return members, nil
+}
+
+type sfParser struct {
+	s string
+	i int
+}
+
+func (p *sfParser) peek() byte {
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *sfParser) consume(c byte) bool {
+	if p.i < len(p.s) && p.s[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *sfParser) skip(chars string) {
+	for p.i < len(p.s) && strings.IndexByte(chars, p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// key parses a dictionary or parameter key.
+func (p *sfParser) key() (string, error) {
+	start := p.i
+	if c := p.peek(); !(c >= 'a' && c <= 'z' || c == '*') {
+		return "", fmt.Errorf("expected a key at offset %d", p.i)
+	}
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("_-.*", c) >= 0) {
+			break
+		}
+		p.i++
+	}
+	return p.s[start:p.i], nil
+}
+
+// innerList parses an inner list, without its parameters.
+func (p *sfParser) innerList() ([]sfItem, error) {
+	p.consume('(')
+	items := []sfItem{}
+	for {
+		p.skip(" ")
+		if p.consume(')') {
+			return items, nil
+		}
+		if p.i == len(p.s) {
+			return nil, errors.New("unterminated inner list")
+		}
+		if len(items) > 0 && p.s[p.i-1] != ' ' {
+			return nil, fmt.Errorf("expected ' ' at offset %d", p.i)
+		}
+
+		var item sfItem
+		var err error
+		if item.kind, item.value, err = p.bareItem(); err != nil {
+			return nil, err
+		}
+		if item.params, err = p.params(); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// params parses parameters. Boolean parameters without a value are "?1".
+func (p *sfParser) params() (map[string]string, error) {
+	var params map[string]string
+	for p.consume(';') {
+		p.skip(" ")
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		value := "?1"
+		if p.consume('=') {
+			if _, value, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// bareItem parses a string, byte sequence, boolean, number or token, and
+// returns its kind and value. Strings are unquoted, and byte sequences are
+// returned without their colons.
+func (p *sfParser) bareItem() (byte, string, error) {
+	start := p.i
+	switch c := p.peek(); {
+	case c == '"':
+		var b strings.Builder
+		for p.i++; p.i < len(p.s); p.i++ {
+			c := p.s[p.i]
+			switch {
+			case c == '"':
+				p.i++
+				return '"', b.String(), nil
+			case c == '\\':
+				p.i++
+				if p.i == len(p.s) || p.s[p.i] != '"' && p.s[p.i] != '\\' {
+					return 0, "", fmt.Errorf("invalid escape at offset %d", p.i)
+				}
+				b.WriteByte(p.s[p.i])
+			case c < 0x20 || c >= 0x7f:
+				return 0, "", fmt.Errorf("invalid character at offset %d", p.i)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return 0, "", errors.New("unterminated string")
+	case c == ':':
+		end := strings.IndexByte(p.s[p.i+1:], ':')
+		if end < 0 {
+			return 0, "", errors.New("unterminated byte sequence")
+		}
+		p.i += end + 2
+		return ':', p.s[start+1 : p.i-1], nil
+	case c == '?':
+		if p.i+1 < len(p.s) && (p.s[p.i+1] == '0' || p.s[p.i+1] == '1') {
+			p.i += 2
+			return '?', p.s[start:p.i], nil
+		}
+		return 0, "", fmt.Errorf("invalid boolean at offset %d", p.i)
+	case c == '-' || c >= '0' && c <= '9':
+		p.i++
+		p.skip("0123456789.")
+		return '0', p.s[start:p.i], nil
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*':
+		for p.i++; p.i < len(p.s); p.i++ {
+			if c := p.s[p.i]; c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),;<=>?@[\]{}`, c) >= 0 {
+				break
+			}
+		}
+		return 't', p.s[start:p.i], nil
+	}
+	return 0, "", fmt.Errorf("unexpected character at offset %d", p.i)
+}
@@ -0,0 +1,236 @@
+package jsonapi
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSignatureTime = time.Unix(1700000000, 0)
+
+// signedResponse returns the response written by j to a GET request to
+// target, with its body.
+func signedResponse(t *testing.T, j *JSONResponder, target string, respond func(j *JSONResponder, w http.ResponseWriter)) (*http.Response, []byte) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	w := httptest.NewRecorder()
+	respond(j, WithRequest(w, r))
+
+	res := w.Result()
+	res.Request = r
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, body
+}
+
+func TestSignResponseHMAC(t *testing.T) {
+	j := &JSONResponder{Signer: &MessageSigner{
+		KeyID: "partner-1",
+		Key:   []byte("secret"),
+		Now:   func() time.Time { return testSignatureTime },
+	}}
+	res, body := signedResponse(t, j, "/users/1", func(j *JSONResponder, w http.ResponseWriter) {
+		j.OK(w, map[string]string{"name": "Ada"})
+	})
+
+	expected := `sig1=("@status" "content-type" "content-digest");created=1700000000;keyid="partner-1";alg="hmac-sha256"`
+	if input := res.Header.Get("Signature-Input"); input != expected {
+		t.Errorf("expected %#v, got %#v", expected, input)
+	}
+	if digest, _ := ContentDigest(body); res.Header.Get("Content-Digest") != digest {
+		t.Errorf("expected %#v, got %#v", digest, res.Header.Get("Content-Digest"))
+	}
+
+	v := &MessageVerifier{
+		Keys:   map[string]interface{}{"partner-1": []byte("secret")},
+		MaxAge: time.Minute,
+		Now:    func() time.Time { return testSignatureTime.Add(time.Second) },
+	}
+	if err := v.VerifyResponse(res, body); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+}
+
+func TestSignResponseEd25519(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := &JSONResponder{
+		ContentDigest: []string{DigestSHA512},
+		Signer: &MessageSigner{
+			Label:      "partner",
+			KeyID:      "ed-1",
+			Key:        private,
+			Components: []string{"@status", "content-digest", "@method;req", "@path;req", "@query;req"},
+			Expires:    time.Minute,
+			Tag:        "jsonapi",
+		},
+	}
+	res, body := signedResponse(t, j, "/users?page=2", func(j *JSONResponder, w http.ResponseWriter) {
+		j.NotFound(w)
+	})
+
+	if digest := res.Header.Get("Content-Digest"); !strings.HasPrefix(digest, "sha-512=:") {
+		t.Errorf("expected a sha-512 digest, got %#v", digest)
+	}
+	if input := res.Header.Get("Signature-Input"); !strings.HasPrefix(input, `partner=("@status" "content-digest" "@method";req "@path";req "@query";req);created=`) ||
+		!strings.HasSuffix(input, `;keyid="ed-1";alg="ed25519";tag="jsonapi"`) {
+		t.Errorf("unexpected Signature-Input %#v", input)
+	}
+
+	v := &MessageVerifier{
+		Label:    "partner",
+		Keys:     map[string]interface{}{"ed-1": public},
+		Required: []string{"@status", "content-digest", "@path;req"},
+	}
+	if err := v.VerifyResponse(res, body); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+
+	res.Request.URL.RawQuery = "page=3"
+	if err := v.VerifyResponse(res, body); !errors.Is(err, ErrSignature) {
+		t.Errorf("expected %#v, got %#v", ErrSignature, err)
+	}
+}
+
+func TestVerifyResponseInvalid(t *testing.T) {
+	key := []byte("secret")
+	sign := func(s *MessageSigner) (*http.Response, []byte) {
+		s.Key, s.KeyID = key, "k"
+		s.Now = func() time.Time { return testSignatureTime }
+		return signedResponse(t, &JSONResponder{Signer: s}, "/", func(j *JSONResponder, w http.ResponseWriter) {
+			j.Created(w, "created")
+		})
+	}
+	verifier := func() *MessageVerifier {
+		return &MessageVerifier{
+			Keys: map[string]interface{}{"k": key},
+			Now:  func() time.Time { return testSignatureTime.Add(time.Hour) },
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		tamper   func(res *http.Response, body []byte, v *MessageVerifier) []byte
+		signer   *MessageSigner
+		expected error
+	}{
+		{"body", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			return []byte(strings.Replace(string(body), "created", "deleted", 1))
+		}, &MessageSigner{}, ErrContentDigest},
+		{"status", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			res.StatusCode = http.StatusOK
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"header", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			res.Header.Set("Content-Type", "text/html")
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"key", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			v.Keys["k"] = []byte("other")
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"unknown key", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			delete(v.Keys, "k")
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"algorithm", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			public, _, _ := ed25519.GenerateKey(nil)
+			v.Keys["k"] = public
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"label", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			v.Label = "sig2"
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"missing", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			res.Header.Del("Signature")
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"max age", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			v.MaxAge = time.Minute
+			return body
+		}, &MessageSigner{}, ErrSignature},
+		{"expired", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			return body
+		}, &MessageSigner{Expires: time.Minute}, ErrSignature},
+		{"not covered", func(res *http.Response, body []byte, v *MessageVerifier) []byte {
+			return body
+		}, &MessageSigner{Components: []string{"@status", "content-type"}}, ErrSignature},
+	} {
+		res, body := sign(test.signer)
+		v := verifier()
+		body = test.tamper(res, body, v)
+
+		if err := v.VerifyResponse(res, body); !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %#v, got %#v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestSignResponseErrors(t *testing.T) {
+	for _, s := range []*MessageSigner{
+		{Key: "secret"},
+		{Key: []byte{}},
+		{Key: []byte("secret"), Components: []string{"x-missing"}},
+		{Key: []byte("secret"), Components: []string{"@method"}},
+		{Key: []byte("secret"), Components: []string{"@status", "@status"}},
+		{Key: []byte("secret"), Components: []string{"Content-Type"}},
+		{Key: []byte("secret"), Components: []string{"@path;req"}},
+		{Key: []byte("secret"), KeyID: "café"},
+	} {
+		if err := s.SignResponse(http.StatusOK, http.Header{"Content-Type": {"application/json"}}, nil); err == nil {
+			t.Errorf("expected an error for %#v, got %#v", s, err)
+		}
+	}
+
+	j := &JSONResponder{Signer: &MessageSigner{Key: "secret"}}
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("expected a panic, got %#v", err)
+		}
+	}()
+	j.OK(httptest.NewRecorder())
+}
+
+func TestParseDictionary(t *testing.T) {
+	// The Signature-Input of RFC 9421, section 4.1, with a second signature.
+	header := `sig1=("@method" "@authority" "@path" "content-digest" "content-length" "content-type");created=1618884473;keyid="test-key-rsa-pss", proxy_sig=("@status";req "x-\"quoted\"");tag="a;b"`
+	members, err := parseDictionary(header)
+	if err != nil {
+		t.Fatalf("expected %#v, got %#v", nil, err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("expected %#v, got %#v", 2, len(members))
+	}
+
+	sig1 := members[0]
+	if expected := header[len("sig1="):strings.Index(header, ", proxy_sig")]; sig1.value != expected {
+		t.Errorf("expected %#v, got %#v", expected, sig1.value)
+	}
+	if len(sig1.items) != 6 || sig1.items[5].value != "content-type" {
+		t.Errorf("expected 6 components, got %#v", sig1.items)
+	}
+	if sig1.params["created"] != "1618884473" || sig1.params["keyid"] != "test-key-rsa-pss" {
+		t.Errorf("unexpected parameters %#v", sig1.params)
+	}
+
+	proxy := members[1]
+	if proxy.items[0].params["req"] != "?1" || proxy.items[1].value != `x-"quoted"` {
+		t.Errorf("unexpected components %#v", proxy.items)
+	}
+
+	for _, invalid := range []string{`sig1=("a""b")`, `sig1=("a"`, `Sig1=:AA==:`, `sig1=:AA==`, `sig1="\x"`, `sig1=?2`} {
+		if _, err := parseDictionary(invalid); err == nil {
+			t.Errorf("expected an error for %#v, got %#v", invalid, err)
+		}
+	}
+}
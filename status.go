@@ -0,0 +1,165 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// StatusClass is the class of a status code, given by its first digit.
+type StatusClass int
+
+// Status code classes, as defined by RFC 9110.
+const (
+	ClassInformational StatusClass = 1
+	ClassSuccessful    StatusClass = 2
+	ClassRedirection   StatusClass = 3
+	ClassClientError   StatusClass = 4
+	ClassServerError   StatusClass = 5
+)
+
+// String returns the name of the class, such as "Client Error".
+func (c StatusClass) String() string {
+	switch c {
+	case ClassInformational:
+		return "Informational"
+	case ClassSuccessful:
+		return "Successful"
+	case ClassRedirection:
+		return "Redirection"
+	case ClassClientError:
+		return "Client Error"
+	case ClassServerError:
+		return "Server Error"
+	}
+	return fmt.Sprintf("StatusClass(%d)", int(c))
+}
+
+// StatusInfo describes a status code.
+type StatusInfo struct {
+	// Code is the status code.
+	Code int
+
+	// Text is the default data of responses with the status code.
+	Text string
+
+	// Class is the class of the status code.
+	Class StatusClass
+
+	// BodyAllowed reports whether responses with the status code may have a
+	// body. Responses that may not are written without one.
+	BodyAllowed bool
+
+	// Cacheable reports whether responses with the status code are
+	// cacheable by default, without explicit freshness information.
+	Cacheable bool
+
+	// Retryable reports whether a request answered with the status code may
+	// succeed if it is retried unchanged.
+	Retryable bool
+
+	// ErrorBody reports whether the body of responses with the status code
+	// is expected to describe an error.
+	ErrorBody bool
+}
+
+var statuses = struct {
+	sync.RWMutex
+	m map[int]StatusInfo
+}{m: make(map[int]StatusInfo)}
+
+func init() {
+	for code := 100; code < 600; code++ {
+		if text := http.StatusText(code); text != "" {
+			registerStatus(defaultStatusInfo(code, text))
+		}
+	}
+
+	switchProxy := defaultStatusInfo(statusSwitchProxy, "Switch Proxy")
+	registerStatus(switchProxy)
+
+	clientClosed := defaultStatusInfo(StatusClientClosedRequest, "Client Closed Request")
+	clientClosed.Retryable = true
+	registerStatus(clientClosed)
+}
+
+// defaultStatusInfo returns the StatusInfo of a status code as defined by
+// RFC 9110, or derived from its class for status codes it does not define.
+func defaultStatusInfo(code int, text string) StatusInfo {
+	info := StatusInfo{
+		Code:        code,
+		Text:        text,
+		Class:       StatusClass(code / 100),
+		BodyAllowed: true,
+	}
+	info.ErrorBody = info.Class == ClassClientError || info.Class == ClassServerError
+
+	switch code {
+	case http.StatusNoContent, http.StatusResetContent, http.StatusNotModified:
+		info.BodyAllowed = false
+	}
+	if info.Class == ClassInformational {
+		info.BodyAllowed = false
+	}
+
+	switch code {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusPartialContent, http.StatusMultipleChoices, http.StatusMovedPermanently,
+		http.StatusPermanentRedirect, http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusGone, http.StatusRequestURITooLong, http.StatusNotImplemented:
+		info.Cacheable = true
+	}
+
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		info.Retryable = true
+	}
+
+	return info
+}
+
+// RegisterStatus adds info to the status code registry, replacing any
+// existing entry for info.Code. It is typically called from an init
+// function, to register nonstandard status codes such as 520, or to change
+// the default text of a standard one.
+//
+// If info.Class is zero, it is derived from info.Code. RegisterStatus panics
+// if info.Code is not a three-digit status code.
+func RegisterStatus(info StatusInfo) {
+	if info.Code < 100 || info.Code > 999 {
+		panic(fmt.Sprintf("jsonapi: invalid status code %d", info.Code))
+	}
+	if info.Class == 0 {
+		info.Class = StatusClass(info.Code / 100)
+	}
+	registerStatus(info)
+}
+
+func registerStatus(info StatusInfo) {
+	statuses.Lock()
+	statuses.m[info.Code] = info
+	statuses.Unlock()
+}
+
+// LookupStatus returns the StatusInfo of code from the registry. If code is
+// not registered, it returns a StatusInfo derived from the class of code, with
+// the name of the class as text, and false.
+func LookupStatus(code int) (StatusInfo, bool) {
+	statuses.RLock()
+	info, ok := statuses.m[code]
+	statuses.RUnlock()
+
+	if !ok {
+		info = defaultStatusInfo(code, StatusClass(code/100).String())
+	}
+	return info, ok
+}
+
+// StatusText returns the text of code from the registry. Unlike
+// http.StatusText, it never returns "": status codes that are not registered
+// get the name of their class, such as "Server Error" for 599.
+func StatusText(code int) string {
+	info, _ := LookupStatus(code)
+	return info.Text
+}
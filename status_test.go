@@ -0,0 +1,105 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLookupStatus(t *testing.T) {
+	for _, test := range []struct {
+		code int
+		info StatusInfo
+		ok   bool
+	}{
+		{
+			code: http.StatusOK,
+			info: StatusInfo{Code: 200, Text: "OK", Class: ClassSuccessful, BodyAllowed: true, Cacheable: true},
+			ok:   true,
+		},
+		{
+			code: http.StatusNoContent,
+			info: StatusInfo{Code: 204, Text: "No Content", Class: ClassSuccessful, Cacheable: true},
+			ok:   true,
+		},
+		{
+			code: http.StatusServiceUnavailable,
+			info: StatusInfo{Code: 503, Text: "Service Unavailable", Class: ClassServerError, BodyAllowed: true, Retryable: true, ErrorBody: true},
+			ok:   true,
+		},
+		{
+			code: StatusClientClosedRequest,
+			info: StatusInfo{Code: 499, Text: "Client Closed Request", Class: ClassClientError, BodyAllowed: true, Retryable: true, ErrorBody: true},
+			ok:   true,
+		},
+		{
+			code: 599,
+			info: StatusInfo{Code: 599, Text: "Server Error", Class: ClassServerError, BodyAllowed: true, ErrorBody: true},
+			ok:   false,
+		},
+	} {
+		info, ok := LookupStatus(test.code)
+		if info != test.info || ok != test.ok {
+			t.Errorf("expected %#v %#v, got %#v %#v", test.info, test.ok, info, ok)
+		}
+	}
+}
+
+func TestRegisterStatus(t *testing.T) {
+	RegisterStatus(StatusInfo{Code: 520, Text: "Web Server Returned an Unknown Error", BodyAllowed: true, ErrorBody: true})
+	defer func() {
+		statuses.Lock()
+		delete(statuses.m, 520)
+		statuses.Unlock()
+	}()
+
+	info, ok := LookupStatus(520)
+	if !ok || info.Class != ClassServerError {
+		t.Errorf("expected registered server error, got %#v %#v", info, ok)
+	}
+
+	w := httptest.NewRecorder()
+	Respond(w, 520)
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "Web Server Returned an Unknown Error" {
+		t.Errorf("expected %#v, got %#v", "Web Server Returned an Unknown Error", resp.Data)
+	}
+}
+
+func TestRegisterStatusPanicsOnInvalidCode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected RegisterStatus to panic for an invalid code")
+		}
+	}()
+	RegisterStatus(StatusInfo{Code: 42})
+}
+
+func TestRespondUnknownStatusText(t *testing.T) {
+	w := httptest.NewRecorder()
+	Respond(w, 599)
+
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "Server Error" {
+		t.Errorf("expected %#v, got %#v", "Server Error", resp.Data)
+	}
+}
+
+func TestRespondWithoutBody(t *testing.T) {
+	for _, code := range []int{http.StatusContinue, http.StatusNoContent, http.StatusResetContent, http.StatusNotModified} {
+		w := httptest.NewRecorder()
+		Respond(w, code, "ignored")
+
+		if w.Body.Len() != 0 {
+			t.Errorf("expected empty body for %#v, got %#v", code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout returns a handler that runs next with a time limit of d, like
+// http.TimeoutHandler. If next does not finish in time, the request is
+// answered with ServiceUnavailable instead.
+//
+// The context of the request passed to next is cancelled once the time limit
+// is reached. The response of next is buffered until it finishes, and writes
+// made after the time limit return http.ErrHandlerTimeout. Responses written
+// by next are request-aware, so they are skipped after the time limit.
+func Timeout(next http.Handler, d time.Duration) http.Handler {
+	return TimeoutStatus(next, d, http.StatusServiceUnavailable)
+}
+
+// TimeoutStatus is like Timeout, but answers requests that time out with
+// the given status code and data, such as GatewayTimeout for handlers that
+// wait on an upstream service.
+func TimeoutStatus(next http.Handler, d time.Duration, code int, data ...interface{}) http.Handler {
+	return (&TimeLimiter{Timeout: d, Status: code, Data: data}).Handler(next)
+}
+
+// TimeLimiter is a middleware that runs handlers with a time limit, see
+// Timeout.
+type TimeLimiter struct {
+	// Timeout is the time limit of the handler.
+	Timeout time.Duration
+
+	// Status is the status code requests that time out are answered with.
+	// It defaults to 503.
+	Status int
+
+	// Data is written with requests that time out.
+	Data []interface{}
+
+	// Responder writes the responses to requests that time out. It
+	// defaults to DefaultResponder.
+	Responder *JSONResponder
+}
+
+// Handler returns a handler that runs next with the time limit of tl.
+func (tl *TimeLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), tl.Timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{header: make(http.Header)}
+
+		// The buffered response of next is written to w at once, so a
+		// Tracker of w would not see its duplicates. They are tracked by the
+		// same Tracker before they are buffered instead.
+		var inner http.ResponseWriter = tw
+		if t := trackerOf(w); t != nil {
+			inner = t.tracker.Wrap(tw, r)
+		}
+
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			next.ServeHTTP(WithRequest(inner, r), r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			dst := w.Header()
+			for k, v := range tw.header {
+				dst[k] = v
+			}
+			if !tw.wroteHeader {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			w.Write(tw.body.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			tw.timedOut = true
+			responderOf(tl.Responder).Respond(w, tl.status(), tl.Data...)
+		}
+	})
+}
+
+func (tl *TimeLimiter) status() int {
+	if tl.Status == 0 {
+		return http.StatusServiceUnavailable
+	}
+	return tl.Status
+}
+
+// timeoutWriter is an http.ResponseWriter that buffers the response of a
+// handler run by a TimeLimiter.
+type timeoutWriter struct {
+	header http.Header
+
+	mu          sync.Mutex
+	body        bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.body.Write(b)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if code < 100 || code > 999 {
+		panic(fmt.Sprintf("invalid WriteHeader code %v", code))
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
@@ -0,0 +1,104 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutPassesThrough(t *testing.T) {
+	h := Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Errorf("expected the request context to have a deadline")
+		}
+		w.Header().Set("X-Foo", "bar")
+		Created(w, "done")
+	}), time.Second)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status code %#v, got %#v", http.StatusCreated, w.Code)
+	}
+	if h := w.Header().Get("X-Foo"); h != "bar" {
+		t.Errorf("expected %#v, got %#v", "bar", h)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "done" {
+		t.Errorf("expected %#v, got %#v", "done", resp.Data)
+	}
+}
+
+func TestTimeoutExpires(t *testing.T) {
+	served := make(chan struct{})
+	late := make(chan error)
+	h := Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write once the timeout response was sent.
+		<-served
+		w.Header().Set("X-Late", "yes")
+		_, err := w.Write([]byte("late"))
+		late <- err
+	}), 10*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	close(served)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %#v, got %#v", http.StatusServiceUnavailable, w.Code)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %#v, got %#v", http.StatusServiceUnavailable, resp.Code)
+	}
+
+	if err := <-late; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("expected %#v, got %#v", http.ErrHandlerTimeout, err)
+	}
+	if w.Header().Get("X-Late") != "" {
+		t.Errorf("expected late headers to be discarded")
+	}
+}
+
+func TestTimeoutStatus(t *testing.T) {
+	h := TimeoutStatus(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}), 10*time.Millisecond, http.StatusGatewayTimeout, "upstream timed out")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status code %#v, got %#v", http.StatusGatewayTimeout, w.Code)
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if resp.Data != "upstream timed out" {
+		t.Errorf("expected %#v, got %#v", "upstream timed out", resp.Data)
+	}
+}
+
+func TestTimeoutPropagatesPanic(t *testing.T) {
+	h := Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), time.Second)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("expected %#v, got %#v", "boom", p)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,189 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// ErrResponseSent is returned by writes that a Tracker refused because the
+// response was already sent.
+var ErrResponseSent = errors.New("jsonapi: response already sent")
+
+// CallSite is the location of a call that wrote a response.
+type CallSite struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// String returns the call site as "file:line (function)".
+func (c CallSite) String() string {
+	return fmt.Sprintf("%s:%d (%s)", c.File, c.Line, c.Function)
+}
+
+// DuplicateResponse describes a write that was refused because the response
+// to its request was already sent.
+type DuplicateResponse struct {
+	Request *http.Request
+
+	// First is where the response was sent.
+	First CallSite
+
+	// Second is where the refused write was made.
+	Second CallSite
+}
+
+// Tracker is a middleware that tracks whether the response to a request was
+// sent, and refuses later attempts to respond, such as falling through from
+// NotFound to OK.
+//
+// Once a response is sent, further responses written with this package and
+// further calls to WriteHeader are dropped, and writes to the body made after
+// a response written with this package return ErrResponseSent. Every refused
+// write is reported with the call sites of both responses. Handlers run by
+// Timeout within a tracked handler are tracked too.
+type Tracker struct {
+	// OnDuplicate is called for every refused write. If nil, refused writes
+	// are logged with the log package.
+	OnDuplicate func(d DuplicateResponse)
+
+	// Panic makes refused writes panic after they are reported, which makes
+	// them fail tests.
+	Panic bool
+}
+
+// Handler returns a handler that tracks the responses written by next.
+func (t *Tracker) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(t.Wrap(w, r), r)
+	})
+}
+
+// Wrap returns an http.ResponseWriter that tracks the response to r written
+// through it.
+func (t *Tracker) Wrap(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	return &trackingWriter{ResponseWriter: w, tracker: t, req: r}
+}
+
+// trackingWriter is an http.ResponseWriter that knows whether the response
+// was sent.
+type trackingWriter struct {
+	http.ResponseWriter
+	tracker *Tracker
+	req     *http.Request
+
+	// sent is set once the status code or body is written, and first is
+	// where that happened.
+	sent  bool
+	first CallSite
+
+	// responding is set while a response is written with this package, and
+	// responded once it is complete.
+	responding bool
+	responded  bool
+}
+
+func (tw *trackingWriter) WriteHeader(code int) {
+	// Informational responses may precede the final response.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		tw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if tw.sent {
+		tw.duplicate()
+		return
+	}
+	tw.send()
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *trackingWriter) Write(b []byte) (int, error) {
+	if tw.responded {
+		tw.duplicate()
+		return 0, ErrResponseSent
+	}
+	if !tw.sent {
+		tw.send()
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for
+// http.ResponseController.
+func (tw *trackingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// begin is called before a response is written with this package. It
+// reports whether the response may be written.
+func (tw *trackingWriter) begin() bool {
+	if tw.sent {
+		tw.duplicate()
+		return false
+	}
+	tw.first = callSite()
+	tw.responding = true
+	return true
+}
+
+// end is called after a response was written with this package.
+func (tw *trackingWriter) end() {
+	tw.responding = false
+	tw.responded = true
+}
+
+// send marks the response as sent. The call site of a response written with
+// this package was already recorded by begin.
+func (tw *trackingWriter) send() {
+	if !tw.responding {
+		tw.first = callSite()
+	}
+	tw.sent = true
+}
+
+func (tw *trackingWriter) duplicate() {
+	d := DuplicateResponse{Request: tw.req, First: tw.first, Second: callSite()}
+
+	if tw.tracker.OnDuplicate != nil {
+		tw.tracker.OnDuplicate(d)
+	} else {
+		log.Printf("jsonapi: response already sent at %s, refused write at %s", d.First, d.Second)
+	}
+
+	if tw.tracker.Panic {
+		panic(fmt.Sprintf("jsonapi: response already sent at %s, refused write at %s", d.First, d.Second))
+	}
+}
+
+// trackerOf returns the trackingWriter w is or wraps, or nil.
+func trackerOf(w http.ResponseWriter) *trackingWriter {
+	var tw *trackingWriter
+	unwrap(w, func(w http.ResponseWriter) bool {
+		tw, _ = w.(*trackingWriter)
+		return tw != nil
+	})
+	return tw
+}
+
+// pkgPrefix is the prefix of the names of the functions of this package.
+var pkgPrefix = reflect.TypeOf(CallSite{}).PkgPath() + "."
+
+// callSite returns the first caller outside this package and net/http.
+// Calls from the tests of this package count as callers outside it.
+func callSite() CallSite {
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "net/http.") ||
+			(strings.HasPrefix(frame.Function, pkgPrefix) && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal || !more {
+			return CallSite{Function: frame.Function, File: frame.File, Line: frame.Line}
+		}
+	}
+}
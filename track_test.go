@@ -0,0 +1,153 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrackerRefusesSecondResponse(t *testing.T) {
+	var dups []DuplicateResponse
+	tracker := &Tracker{OnDuplicate: func(d DuplicateResponse) {
+		dups = append(dups, d)
+	}}
+	h := tracker.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NotFound(w)
+		OK(w, "found after all")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status code %#v, got %#v", http.StatusNotFound, w.Code)
+	}
+	dec := json.NewDecoder(w.Body)
+	resp := &Response{}
+	if err := dec.Decode(resp); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if err := dec.Decode(&Response{}); err != io.EOF {
+		t.Errorf("expected a single JSON document, got %#v", err)
+	}
+
+	if len(dups) != 1 {
+		t.Fatalf("expected 1 duplicate, got %#v", len(dups))
+	}
+	d := dups[0]
+	if !strings.HasSuffix(d.First.File, "track_test.go") || !strings.HasSuffix(d.Second.File, "track_test.go") {
+		t.Errorf("expected call sites in track_test.go, got %s and %s", d.First, d.Second)
+	}
+	if d.Second.Line != d.First.Line+1 {
+		t.Errorf("expected consecutive call sites, got %s and %s", d.First, d.Second)
+	}
+}
+
+func TestTrackerWithTimeout(t *testing.T) {
+	var dups []DuplicateResponse
+	tracker := &Tracker{OnDuplicate: func(d DuplicateResponse) {
+		dups = append(dups, d)
+	}}
+	h := tracker.Handler(Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NotFound(w)
+		OK(w, "found after all")
+	}), time.Second))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status code %#v, got %#v", http.StatusNotFound, w.Code)
+	}
+	dec := json.NewDecoder(w.Body)
+	if err := dec.Decode(&Response{}); err != nil {
+		t.Errorf("expected %#v, got %#v", nil, err)
+	}
+	if err := dec.Decode(&Response{}); err != io.EOF {
+		t.Errorf("expected a single JSON document, got %#v", err)
+	}
+	if len(dups) != 1 {
+		t.Errorf("expected 1 duplicate, got %#v", len(dups))
+	}
+}
+
+func TestTrackerRefusesWritesAfterResponse(t *testing.T) {
+	var dups []DuplicateResponse
+	tracker := &Tracker{OnDuplicate: func(d DuplicateResponse) {
+		dups = append(dups, d)
+	}}
+
+	w := httptest.NewRecorder()
+	tw := tracker.Wrap(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	BadRequest(tw)
+	if _, err := tw.Write([]byte("oops")); !errors.Is(err, ErrResponseSent) {
+		t.Errorf("expected %#v, got %#v", ErrResponseSent, err)
+	}
+	tw.WriteHeader(http.StatusOK)
+
+	if len(dups) != 2 {
+		t.Errorf("expected 2 duplicates, got %#v", len(dups))
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status code %#v, got %#v", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTrackerAllowsStreamedBody(t *testing.T) {
+	tracker := &Tracker{OnDuplicate: func(d DuplicateResponse) {
+		t.Errorf("unexpected duplicate response: %s and %s", d.First, d.Second)
+	}}
+
+	w := httptest.NewRecorder()
+	tw := tracker.Wrap(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	tw.WriteHeader(http.StatusOK)
+	io.WriteString(tw, "chunk 1\n")
+	io.WriteString(tw, "chunk 2\n")
+
+	if w.Body.String() != "chunk 1\nchunk 2\n" {
+		t.Errorf("expected %#v, got %#v", "chunk 1\nchunk 2\n", w.Body.String())
+	}
+}
+
+func TestTrackerRefusesResponseAfterPlainWrite(t *testing.T) {
+	var dups []DuplicateResponse
+	tracker := &Tracker{OnDuplicate: func(d DuplicateResponse) {
+		dups = append(dups, d)
+	}}
+
+	w := httptest.NewRecorder()
+	tw := tracker.Wrap(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	http.Error(tw, "nope", http.StatusForbidden)
+	InternalServerError(tw)
+
+	if len(dups) != 1 {
+		t.Fatalf("expected 1 duplicate, got %#v", len(dups))
+	}
+	if !strings.HasSuffix(dups[0].First.File, "track_test.go") {
+		t.Errorf("expected the first call site outside net/http, got %s", dups[0].First)
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status code %#v, got %#v", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestTrackerPanics(t *testing.T) {
+	tracker := &Tracker{Panic: true, OnDuplicate: func(d DuplicateResponse) {}}
+	tw := tracker.Wrap(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a duplicate response to panic")
+		}
+	}()
+	OK(tw)
+	OK(tw)
+}